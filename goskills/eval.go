@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills/eval"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"os"
+)
+
+var evalCmd = &command{
+	name:  "eval",
+	short: "backtest a calculator's predictions on a match log",
+	run:   runEval,
+}
+
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: goskills eval [flags] matchlog\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	gi := gameInfoFlags(fs)
+	calcName := fs.String("calc", "twoteam", "calculator to evaluate: `twoteam or twoplayer`")
+	bins := fs.Int("bins", 10, "number of bins of the calibration table")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *bins < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var calc matchlog.Predictor
+	switch *calcName {
+	case "twoteam":
+		calc = &trueskill.TwoTeamCalc{}
+	case "twoplayer":
+		calc = &trueskill.TwoPlayerCalc{}
+	default:
+		return fmt.Errorf("unknown calculator %q", *calcName)
+	}
+
+	entries, err := readLog(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return eval.Backtest(calc, gi, entries, *bins).Write(os.Stdout)
+}
+
+// Reads all entries of the match log in the named file; "-" reads standard input.
+func readLog(name string) ([]*matchlog.Entry, error) {
+	if name == "-" {
+		return matchlog.NewReader(os.Stdin).ReadAll()
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return matchlog.NewReader(f).ReadAll()
+}
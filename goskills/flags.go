@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"strconv"
+	"strings"
+)
+
+// Registers flags for each GameInfo parameter, defaulting to skills.DefaultGameInfo.
+func gameInfoFlags(fs *flag.FlagSet) *skills.GameInfo {
+	gi := *skills.DefaultGameInfo
+	fs.Float64Var(&gi.InitialMean, "mu", gi.InitialMean, "initial mean of new players")
+	fs.Float64Var(&gi.InitialStddev, "sigma", gi.InitialStddev, "initial standard deviation of new players")
+	fs.Float64Var(&gi.Beta, "beta", gi.Beta, "performance standard deviation")
+	fs.Float64Var(&gi.DynamicsFactor, "tau", gi.DynamicsFactor, "dynamics factor added to each prior")
+	fs.Float64Var(&gi.DrawProbability, "draw", gi.DrawProbability, "probability of a draw")
+	fs.Float64Var(&gi.InactivityRate, "inactivity-rate", gi.InactivityRate, "variance added per day without playing")
+	fs.Float64Var(&gi.InactivityCap, "inactivity-cap", gi.InactivityCap, "maximum variance added for inactivity (0 for no cap)")
+	return &gi
+}
+
+// Parses a team written as comma separated players. Each player is a name
+// optionally followed by ":mean:stddev"; players without a rating get the
+// default rating.
+func parseTeam(gi *skills.GameInfo, s string) (skills.Team, error) {
+	team := skills.NewTeam()
+	for _, ps := range strings.Split(s, ",") {
+		fields := strings.Split(ps, ":")
+		if fields[0] == "" {
+			return team, fmt.Errorf("missing player name in team %q", s)
+		}
+
+		r := gi.DefaultRating()
+		switch len(fields) {
+		case 1:
+		case 3:
+			mean, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return team, err
+			}
+			stddev, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return team, err
+			}
+			r = skills.NewRating(mean, stddev)
+		default:
+			return team, fmt.Errorf("player %q is not name or name:mean:stddev", ps)
+		}
+
+		team.AddPlayer(*skills.NewPlayer(fields[0]), r)
+	}
+	return team, nil
+}
+
+// Parses comma separated ranks.
+func parseRanks(s string) ([]int, error) {
+	var ranks []int
+	for _, f := range strings.Split(s, ",") {
+		r, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		ranks = append(ranks, r)
+	}
+	return ranks, nil
+}
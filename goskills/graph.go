@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"os"
+)
+
+var graphCmd = &command{
+	name:  "graph",
+	short: "render the factor graph of one match as Graphviz DOT",
+	run:   runGraph,
+}
+
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: goskills graph [flags] team1 team2\n\nEach team is name[:mean:stddev],... Flags:")
+		fs.PrintDefaults()
+	}
+	gi := gameInfoFlags(fs)
+	out := fs.String("o", "", "write the graph to `file` instead of standard output")
+	rankList := fs.String("ranks", "1,2", "comma separated `ranks` of the teams")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	teams := make([]skills.Team, fs.NArg())
+	for i, arg := range fs.Args() {
+		var err error
+		if teams[i], err = parseTeam(gi, arg); err != nil {
+			return err
+		}
+	}
+	ranks, err := parseRanks(*rankList)
+	if err != nil {
+		return err
+	}
+	if len(ranks) != len(teams) {
+		return fmt.Errorf("%v ranks given for %v teams", len(ranks), len(teams))
+	}
+
+	g := trueskill.TwoTeamGraph(gi, teams, ranks...)
+
+	if *out == "" {
+		return g.WriteDot(os.Stdout)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := g.WriteDot(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+// Command goskills works with TrueSkill ratings from the command line.
+//
+// Usage:
+//
+//	goskills command [arguments]
+//
+// The commands are:
+//
+//	eval    backtest a calculator's predictions on a match log
+//	graph   render the factor graph of one match as Graphviz DOT
+//
+// Run "goskills command -h" for the arguments of a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(args []string) error
+}
+
+var commands = []*command{
+	evalCmd,
+	graphCmd,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: goskills command [arguments]\n\nThe commands are:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-7v %v\n", c.name, c.short)
+	}
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+
+	for _, c := range commands {
+		if c.name == flag.Arg(0) {
+			if err := c.run(flag.Args()[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "goskills %v: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "goskills: unknown command %q\n", flag.Arg(0))
+	usage()
+}
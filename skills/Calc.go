@@ -19,6 +19,14 @@ type Calc interface {
 	CalcNewRatings(gi *GameInfo, priors []Team, teamRanks ...int) PlayerRatings
 
 	// Calculates the match quality as the likelihood of all teams
-	// drawing (0% = bad, 100% = well matched).
+	// drawing (0% = bad, 100% = well matched). The ratings are taken as
+	// given; see Match.Quality to grow them for inactivity first.
 	CalcMatchQual(gi *GameInfo, teams []Team) float64
 }
+
+// Methods for calculators that can predict the outcome of a match.
+type OutcomePredictor interface {
+	// Calculates the probabilities that the first team wins, that the teams
+	// draw and that the first team loses; they sum to 1.
+	CalcOutcomeProbs(gi *GameInfo, teams []Team) (win, draw, lose float64)
+}
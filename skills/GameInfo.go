@@ -1,5 +1,11 @@
 package skills
 
+import (
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"time"
+)
+
 const (
 	defaultInitialMean     = 25.0
 	defaultDrawProbability = 0.10
@@ -14,12 +20,91 @@ type GameInfo struct {
 	InitialStddev   float64
 	Beta            float64
 	DynamicsFactor  float64
+
+	// Variance added to a player's prior per day since their last game, up
+	// to InactivityCap (zero means no cap). Zero disables the growth, leaving
+	// only the fixed DynamicsFactor added per match.
+	InactivityRate float64
+	InactivityCap  float64
+
+	// A fixed amount added to the performance of the first team of every
+	// match, such as the home side or white. See also Match.Advantage.
+	Advantage float64
+
+	// The probability that a match's outcome has nothing to do with the
+	// players' skills, e.g. because of a disconnect or griefing. Such
+	// outcomes are modeled as random, so the more unlikely a result, the
+	// more it is put down to chance and the less it moves ratings. Zero
+	// gives the plain Gaussian model.
+	OutlierProbability float64
+
+	// The distribution of performance differences; nil means the Gaussian
+	// of TrueSkill. numerics.LogisticCurve{} gives Elo's logistic curve.
+	Curve numerics.Curve
+
+	// How matches where players left are rated; nil rates them like any
+	// other.
+	Leavers *LeaverPolicy
+
+	// Whether calculators look the Gaussian corrections of ratings up in
+	// precomputed tables rather than computing them, which is faster and
+	// agrees with them to about 1e-6. Unused with outliers or a Curve other
+	// than numerics.GaussCurve.
+	TabulatedCorrections bool
 }
 
 func (this *GameInfo) DefaultRating() Rating {
 	return NewRating(this.InitialMean, this.InitialStddev)
 }
 
+// Returns the variance of a player's performance around their skill: the
+// square of their own beta if they have one, otherwise of the game's.
+func (this *GameInfo) PerformanceVariance(r Rating) float64 {
+	if r.beta > 0 {
+		return numerics.Sqr(r.beta)
+	}
+	return numerics.Sqr(this.Beta)
+}
+
+// A RatingAccumulator summing the players' performance variances.
+func (this *GameInfo) PerformanceVarianceSum(r Rating, a float64) float64 {
+	return a + this.PerformanceVariance(r)
+}
+
+// Returns the rating with its variance grown for the time between the
+// player's last game and at. Ratings without a last played time, and matches
+// without a time, are returned unchanged.
+func (this *GameInfo) InactivePrior(r Rating, at time.Time) Rating {
+	if this.InactivityRate <= 0 || r.lastPlayed.IsZero() || at.IsZero() || !at.After(r.lastPlayed) {
+		return r
+	}
+	growth := this.InactivityRate * at.Sub(r.lastPlayed).Hours() / 24
+	if this.InactivityCap > 0 {
+		growth = math.Min(growth, this.InactivityCap)
+	}
+	r.stddev = math.Sqrt(r.Variance() + growth)
+	return r
+}
+
+// Returns copies of the teams with every rating replaced by its InactivePrior
+// at the given time, except those of anchored players. The teams are returned
+// as is if there is no growth.
+func (this *GameInfo) InactivePriors(teams []Team, at time.Time) []Team {
+	if this.InactivityRate <= 0 || at.IsZero() {
+		return teams
+	}
+	grown := make([]Team, len(teams))
+	for i, t := range teams {
+		grown[i] = t.MapRatings(func(p Player, r Rating) Rating {
+			if t.Attrs(p).Anchor != NotAnchored {
+				return r
+			}
+			return this.InactivePrior(r, at)
+		})
+	}
+	return grown
+}
+
 var DefaultGameInfo = &GameInfo{
 	InitialMean:     defaultInitialMean,
 	DrawProbability: defaultDrawProbability,
@@ -0,0 +1,62 @@
+package skills
+
+import (
+	"math"
+)
+
+// Whether a player saw a match through.
+type Status int
+
+const (
+	// The player played the whole match.
+	Completed Status = iota
+
+	// The player quit the match; PlayerAttrs.LeftAt gives when.
+	Left
+
+	// The player was absent or idle for the whole match.
+	AFK
+)
+
+// How calculators rate matches where players left (see Status). Leavers are
+// rated as having lost, whatever the result of their team, while the others
+// are rated on the result with a reduced weight (see Match.Weight).
+//
+// The weights apply to a team whose first leaver left at the start of the
+// match. The later they left, the closer the weight gets to 1.
+type LeaverPolicy struct {
+	// The weight of the update of the leavers' teammates; 0 leaves their
+	// ratings unchanged.
+	Teammates float64
+
+	// The weight of the update of the leavers' opponents.
+	Opponents float64
+}
+
+// Returns the weight of the update of the players of self who completed a
+// match against other.
+func (this *LeaverPolicy) Weight(self, other Team) float64 {
+	w := 1.0
+	if t, ok := self.LeftAt(); ok {
+		w *= this.Teammates + (1-this.Teammates)*t
+	}
+	if t, ok := other.LeftAt(); ok {
+		w *= this.Opponents + (1-this.Opponents)*t
+	}
+	return w
+}
+
+// Returns the fraction of the match the team played before its first player
+// left, and whether any player left.
+func (t Team) LeftAt() (float64, bool) {
+	at, left := 1.0, false
+	for _, p := range t.Players() {
+		switch a := t.Attrs(p); a.Status {
+		case Left:
+			at, left = math.Min(at, a.LeftAt), true
+		case AFK:
+			at, left = 0, true
+		}
+	}
+	return at, left
+}
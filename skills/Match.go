@@ -0,0 +1,123 @@
+package skills
+
+import (
+	"time"
+)
+
+// A match to be rated, with everything calculators may take into account
+// beyond the teams and their ranks.
+type Match struct {
+	Teams []Team
+	Ranks []int
+
+	// When the match was played. New ratings are stamped with it and priors
+	// grow with the time since each player's last game (see
+	// GameInfo.InactivityRate). The zero time disables both.
+	Time time.Time
+
+	// The advantage of the first team (in the order given) as a Gaussian
+	// added to its performance, overriding GameInfo.Advantage when set.
+	// Calculators that support it replace the rating with its posterior, so
+	// an advantage learned from every result can be carried from match to
+	// match.
+	Advantage *Rating
+
+	// The number of teams whose places are known, counting from first
+	// place. The other teams are only known to have placed below all of
+	// them, in an unknown order: they must be ranked below the known teams
+	// and their ranks among themselves are ignored rather than taken as
+	// draws. Zero means every place is known. It makes no difference to
+	// matches of two teams.
+	Known int
+
+	// How strongly the match counts, e.g. more than 1 for a tournament final
+	// and less for a casual game. The likelihood of the result is tempered
+	// by it, so that 0 leaves ratings unchanged and 1 is the usual update.
+	// The dynamics added to the priors are scaled by it up to 1. Nil means 1.
+	Weight *float64
+}
+
+// Creates a match between the teams with the given ranks; use 1 for first
+// place, repeat the number for a tie (e.g. 1, 2, 2).
+func NewMatch(teams []Team, ranks ...int) *Match {
+	return &Match{
+		Teams: teams,
+		Ranks: ranks,
+	}
+}
+
+// Creates a match where only the winner is known to have beaten all the
+// other teams.
+func NewWinnerMatch(teams []Team, winner int) *Match {
+	ranks := make([]int, len(teams))
+	for i := range ranks {
+		ranks[i] = 2
+	}
+	ranks[winner] = 1
+	return NewPartialMatch(teams, 1, ranks...)
+}
+
+// Creates a match where only the first known places are known; the ranks of
+// the other teams need only be below theirs.
+func NewPartialMatch(teams []Team, known int, ranks ...int) *Match {
+	m := NewMatch(teams, ranks...)
+	m.Known = known
+	return m
+}
+
+// Returns the advantage of the first team: the match's own if it has one,
+// otherwise the game's fixed advantage.
+func (m *Match) FirstTeamAdvantage(gi *GameInfo) Rating {
+	if m.Advantage != nil {
+		return *m.Advantage
+	}
+	return NewRating(gi.Advantage, 0)
+}
+
+// Returns the quality of the match as calc.CalcMatchQual does, from the
+// ratings CalcMatch would rate it from. A MatchQualCalc also takes the rest of
+// the match into account, like its advantage; other calculators get the
+// ratings grown for inactivity up to the match's time.
+func (m *Match) Quality(calc Calc, gi *GameInfo) float64 {
+	if mq, ok := calc.(MatchQualCalc); ok {
+		return mq.MatchQual(gi, m)
+	}
+	return calc.CalcMatchQual(gi, gi.InactivePriors(m.Teams, m.Time))
+}
+
+// Returns the weight of the match, 1 if it has none.
+func (m *Match) MatchWeight() float64 {
+	if m.Weight == nil {
+		return 1
+	}
+	return *m.Weight
+}
+
+// Methods required to calculate skills from a full match description.
+type MatchCalc interface {
+	Calc
+
+	// Calculates new ratings for the players of the match.
+	CalcMatch(gi *GameInfo, m *Match) PlayerRatings
+}
+
+// Methods for calculators that take the whole match into account for its
+// quality.
+type MatchQualCalc interface {
+	Calc
+
+	// Calculates the match quality like CalcMatchQual, from the ratings and
+	// the advantage CalcMatch would rate the match with.
+	MatchQual(gi *GameInfo, m *Match) float64
+}
+
+// Methods for calculators that report what they updated each rating from.
+type UpdateCalc interface {
+	MatchCalc
+
+	// Calculates new ratings like CalcMatch, along with the prior each was
+	// updated from: the player's rating after any growth with inactivity,
+	// with the dynamics of the match added. A posterior divided by its prior
+	// is the evidence of the match alone.
+	CalcUpdate(gi *GameInfo, m *Match) (newRatings, priors PlayerRatings)
+}
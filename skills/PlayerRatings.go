@@ -1,5 +1,9 @@
 package skills
 
+import (
+	"sort"
+)
+
 type PlayerRatings map[Player]Rating
 
 type RatingAccumulator func(r Rating, a float64) float64
@@ -10,3 +14,30 @@ func (pr PlayerRatings) Accum(f RatingAccumulator) (a float64) {
 	}
 	return
 }
+
+// A RatingStore holds the current rating of each player.
+type RatingStore interface {
+	// Returns the rating of a player and whether the player has one.
+	Rating(p Player) (Rating, bool)
+
+	SetRating(p Player, r Rating)
+}
+
+// Returns the players sorted by name.
+func (pr PlayerRatings) Players() []Player {
+	ps := []Player{}
+	for p := range pr {
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].String() < ps[j].String() })
+	return ps
+}
+
+func (pr PlayerRatings) Rating(p Player) (Rating, bool) {
+	r, ok := pr[p]
+	return r, ok
+}
+
+func (pr PlayerRatings) SetRating(p Player, r Rating) {
+	pr[p] = r
+}
@@ -3,15 +3,49 @@ package skills
 import (
 	"fmt"
 	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"time"
 )
 
 type Rating struct {
-	mean   float64
-	stddev float64
+	mean       float64
+	stddev     float64
+	lastPlayed time.Time
+	beta       float64
 }
 
 func NewRating(mean, stddev float64) Rating {
-	return Rating{mean, stddev}
+	return Rating{mean: mean, stddev: stddev}
+}
+
+// Returns a copy of the rating stamped with the time of the player's last game.
+func (r Rating) PlayedAt(t time.Time) Rating {
+	r.lastPlayed = t
+	return r
+}
+
+// Returns when the player last played, or the zero time if unknown.
+func (r Rating) LastPlayed() time.Time {
+	return r.lastPlayed
+}
+
+// Returns a copy of the rating with the player's own performance standard
+// deviation, used by calculators instead of GameInfo.Beta.
+func (r Rating) WithBeta(beta float64) Rating {
+	r.beta = beta
+	return r
+}
+
+// Returns the player's own performance standard deviation, or 0 if they use
+// the game's.
+func (r Rating) Beta() float64 {
+	return r.beta
+}
+
+// Reports whether two ratings are the same, including when the player last
+// played. Unlike ==, it compares the times as instants, so ratings stamped
+// with the same time in different locations are equal.
+func (r Rating) Equal(s Rating) bool {
+	return r.mean == s.mean && r.stddev == s.stddev && r.beta == s.beta && r.lastPlayed.Equal(s.lastPlayed)
 }
 
 func (r Rating) Mean() float64 {
@@ -26,6 +60,14 @@ func (r Rating) Variance() float64 {
 	return numerics.Sqr(r.stddev)
 }
 
+// The number of standard deviations subtracted from the mean to achieve a conservative rating.
+const ConservativeStddevMultiplier = 3
+
+// A conservative estimate of skill based on the mean and standard deviation.
+func (r Rating) ConservativeRating() float64 {
+	return r.mean - ConservativeStddevMultiplier*r.stddev
+}
+
 func (r Rating) String() string {
 	return fmt.Sprintf("{μ:%.6g σ:%.6g}", r.mean, r.stddev)
 }
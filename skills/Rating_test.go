@@ -0,0 +1,19 @@
+package skills
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRatingEqual(t *testing.T) {
+	at := time.Date(2013, 6, 1, 12, 0, 0, 0, time.UTC)
+	r := NewRating(25, 3).PlayedAt(at)
+	if !r.Equal(NewRating(25, 3).PlayedAt(at.In(time.FixedZone("CEST", 2*60*60)))) {
+		t.Errorf("%v not equal to itself in another location", r)
+	}
+	for _, s := range []Rating{NewRating(25, 3), r.PlayedAt(at.Add(time.Second)), r.WithBeta(4), NewRating(25, 2).PlayedAt(at)} {
+		if r.Equal(s) {
+			t.Errorf("%v equal to %v last played %v", r, s, s.LastPlayed())
+		}
+	}
+}
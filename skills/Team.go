@@ -1,29 +1,190 @@
 package skills
 
+import (
+	"bytes"
+	"fmt"
+)
+
+// A Team is an ordered roster of players and their ratings. Players are kept
+// in the order they were added, so iterating a team gives the same order on
+// every run.
 type Team struct {
 	PlayerRatings
+	roster *roster
+}
+
+// The players of a team in order, with their positional attributes. It is
+// shared by copies of a Team the same way its PlayerRatings map is.
+//
+// Players added to or deleted from the PlayerRatings map directly are only
+// reconciled with it by Reindex.
+type roster struct {
+	players []Player
+	attrs   []PlayerAttrs
+	index   map[Player]int
 }
 
+// Per-player attributes of a team member.
+type PlayerAttrs struct {
+	// The fraction of the match the player took part in, for calculators
+	// supporting partial play (see PartialPlay). AddPlayer sets it to 1.
+	Weight float64
+
+	// The role or position the player played, e.g. "healer" or "goalkeeper".
+	Role string
+
+	// Whether the player completed the match or left it.
+	Status Status
+
+	// For a player who Left, the fraction of the match played before leaving.
+	LeftAt float64
+
+	// Whether the player's rating is held fixed, e.g. for a bot of known skill.
+	Anchor Anchor
+}
+
+// How a player's rating is held fixed.
+type Anchor int
+
+const (
+	// The player is rated as usual.
+	NotAnchored Anchor = iota
+
+	// The player counts in the match as usual, but calculators return their
+	// prior rating unchanged and it does not grow with inactivity.
+	Anchored
+
+	// As Anchored, and the player's skill is also taken to be exactly their
+	// mean: their standard deviation counts as 0 in the match.
+	AnchoredExact
+)
+
 func NewTeam() Team {
-	return Team{make(PlayerRatings)}
+	return Team{make(PlayerRatings), &roster{index: make(map[Player]int)}}
 }
 
+// Adds a player to the end of the team with a weight of 1 and no role.
 func (t Team) AddPlayer(p Player, r Rating) {
+	t.AddPlayerAttrs(p, r, PlayerAttrs{Weight: 1})
+}
+
+// Adds a player to the end of the team with the given attributes. Adding a
+// player already on the team replaces their rating and attributes but keeps
+// their position.
+func (t Team) AddPlayerAttrs(p Player, r Rating, a PlayerAttrs) {
 	t.PlayerRatings[p] = r
+	if t.roster == nil {
+		return
+	}
+	if i, ok := t.roster.index[p]; ok {
+		t.roster.attrs[i] = a
+		return
+	}
+	t.roster.add(p, a)
+}
+
+func (r *roster) add(p Player, a PlayerAttrs) {
+	r.index[p] = len(r.players)
+	r.players = append(r.players, p)
+	r.attrs = append(r.attrs, a)
+}
+
+// Brings the order of the team in line with its PlayerRatings map after
+// players were added to or deleted from the map directly: deleted players
+// drop out of the order and new ones follow the others, sorted by name, with
+// a weight of 1 and no role. Like AddPlayer it must not be called while the
+// team is being read.
+func (t Team) Reindex() {
+	r := t.roster
+	if r == nil {
+		return
+	}
+	players, attrs := r.players, r.attrs
+	r.players, r.attrs = nil, nil
+	r.index = make(map[Player]int, len(t.PlayerRatings))
+	for i, p := range players {
+		if _, ok := t.PlayerRatings[p]; ok {
+			r.add(p, attrs[i])
+		}
+	}
+	for _, p := range t.PlayerRatings.Players() {
+		if _, ok := r.index[p]; !ok {
+			r.add(p, PlayerAttrs{Weight: 1})
+		}
+	}
 }
 
 func (t Team) PlayerCount() int {
+	if t.roster != nil {
+		return len(t.roster.players)
+	}
 	return len(t.PlayerRatings)
 }
 
+// Returns the players in the order they were added. Teams not created by
+// NewTeam have no order of their own and return their players sorted by name.
 func (t Team) Players() []Player {
-	ps := []Player{}
-	for p := range t.PlayerRatings {
-		ps = append(ps, p)
+	if r := t.roster; r != nil {
+		return append([]Player{}, r.players...)
+	}
+	return t.PlayerRatings.Players()
+}
+
+// Returns the i-th player of the team.
+func (t Team) PlayerAt(i int) Player {
+	if r := t.roster; r != nil {
+		return r.players[i]
 	}
-	return ps
+	return t.Players()[i]
 }
 
 func (t Team) PlayerRating(p Player) Rating {
 	return t.PlayerRatings[p]
 }
+
+// Returns the attributes of a player on the team. Players added without
+// attributes have a weight of 1 and no role.
+func (t Team) Attrs(p Player) PlayerAttrs {
+	if r := t.roster; r != nil {
+		if i, ok := r.index[p]; ok {
+			return r.attrs[i]
+		}
+	}
+	return PlayerAttrs{Weight: 1}
+}
+
+// Accumulates over the players' ratings in team order, so sums are the same
+// on every run.
+func (t Team) Accum(f RatingAccumulator) (a float64) {
+	players := t.PlayerRatings.Players()
+	if r := t.roster; r != nil {
+		players = r.players
+	}
+	for _, p := range players {
+		a = f(t.PlayerRatings[p], a)
+	}
+	return
+}
+
+// Returns a new team with the same players, order and attributes, and each
+// rating replaced by f(player, rating).
+func (t Team) MapRatings(f func(p Player, r Rating) Rating) Team {
+	m := NewTeam()
+	for _, p := range t.Players() {
+		m.AddPlayerAttrs(p, f(p, t.PlayerRatings[p]), t.Attrs(p))
+	}
+	return m
+}
+
+func (t Team) String() string {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, p := range t.Players() {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%v:%v", p, t.PlayerRatings[p])
+	}
+	b.WriteString("]")
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package skills
+
+import (
+	"testing"
+)
+
+func TestTeamOrder(t *testing.T) {
+	team := NewTeam()
+	for _, id := range []int{5, 3, 9, 1, 7} {
+		team.AddPlayer(*NewPlayer(id), NewRating(float64(id), 1))
+	}
+	team.AddPlayerAttrs(*NewPlayer(9), NewRating(10, 1), PlayerAttrs{Weight: 0.5, Role: "healer"})
+
+	for run := 0; run < 10; run++ {
+		ps := team.Players()
+		if s := team.String(); s != "[5:{μ:5 σ:1} 3:{μ:3 σ:1} 9:{μ:10 σ:1} 1:{μ:1 σ:1} 7:{μ:7 σ:1}]" {
+			t.Fatalf("team = %v", s)
+		}
+		if len(ps) != 5 || ps[2] != *NewPlayer(9) || team.PlayerAt(4) != *NewPlayer(7) {
+			t.Fatalf("Players() = %v", ps)
+		}
+	}
+
+	if a := team.Attrs(*NewPlayer(9)); a.Weight != 0.5 || a.Role != "healer" {
+		t.Errorf("Attrs(9) = %+v", a)
+	}
+	if a := team.Attrs(*NewPlayer(5)); a.Weight != 1 || a.Role != "" {
+		t.Errorf("Attrs(5) = %+v", a)
+	}
+
+	doubled := team.MapRatings(func(p Player, r Rating) Rating { return NewRating(2*r.Mean(), r.Stddev()) })
+	if s := doubled.String(); s != "[5:{μ:10 σ:1} 3:{μ:6 σ:1} 9:{μ:20 σ:1} 1:{μ:2 σ:1} 7:{μ:14 σ:1}]" {
+		t.Errorf("mapped team = %v", s)
+	}
+	if a := doubled.Attrs(*NewPlayer(9)); a.Role != "healer" {
+		t.Errorf("mapped Attrs(9) = %+v", a)
+	}
+	if m := team.Accum(MeanSum); m != 26 {
+		t.Errorf("mean sum = %v, want %v", m, 26)
+	}
+}
+
+func TestTeamWithoutRoster(t *testing.T) {
+	team := Team{PlayerRatings: PlayerRatings{*NewPlayer("b"): NewRating(1, 1), *NewPlayer("a"): NewRating(2, 1)}}
+	team.AddPlayer(*NewPlayer("c"), NewRating(3, 1))
+	if s := team.String(); s != "[a:{μ:2 σ:1} b:{μ:1 σ:1} c:{μ:3 σ:1}]" {
+		t.Errorf("team = %v", s)
+	}
+}
+
+func TestTeamMapEdits(t *testing.T) {
+	team := NewTeam()
+	for _, id := range []string{"d", "a", "c"} {
+		team.AddPlayerAttrs(*NewPlayer(id), NewRating(1, 1), PlayerAttrs{Weight: 1, Role: id})
+	}
+
+	// Writes to the map show up in the roster once reindexed
+	delete(team.PlayerRatings, *NewPlayer("a"))
+	team.PlayerRatings[*NewPlayer("f")] = NewRating(2, 1)
+	team.PlayerRatings[*NewPlayer("b")] = NewRating(3, 1)
+	if n := team.PlayerCount(); n != 3 {
+		t.Errorf("%v players before reindexing, want 3", n)
+	}
+	team.Reindex()
+	if s := team.String(); s != "[d:{μ:1 σ:1} c:{μ:1 σ:1} b:{μ:3 σ:1} f:{μ:2 σ:1}]" {
+		t.Errorf("team = %v", s)
+	}
+	if a := team.Attrs(*NewPlayer("c")); a.Role != "c" {
+		t.Errorf("Attrs(c) = %+v", a)
+	}
+	if a := team.Attrs(*NewPlayer("f")); a.Weight != 1 || a.Role != "" {
+		t.Errorf("Attrs(f) = %+v", a)
+	}
+	if n, m := team.PlayerCount(), team.Accum(MeanSum); n != 4 || m != 7 || team.PlayerAt(3) != *NewPlayer("f") {
+		t.Errorf("%v players with mean sum %v", n, m)
+	}
+}
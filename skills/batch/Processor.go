@@ -0,0 +1,206 @@
+// Package batch rates large streams of matches on a pool of workers.
+//
+// Matches are rated concurrently when they have no players in common, but
+// each match still sees exactly the ratings it would see if the stream were
+// rated one match at a time: a match is only started once every earlier
+// match sharing one of its players has finished.
+package batch
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"io"
+	"runtime"
+	"time"
+)
+
+// A Source yields matches in the order they must be rated and io.EOF after
+// the last one. *matchlog.Reader is a Source.
+type Source interface {
+	Read() (*matchlog.Entry, error)
+}
+
+// A Processor rates a stream of matches and writes the new ratings to a store.
+// The store is only accessed from the goroutine calling Run, so it does not
+// need to be safe for concurrent use.
+//
+// Matches are rated concurrently with Calc, which must then be safe for
+// concurrent use, as the calculators of package trueskill are. Calculators
+// wrapping another one (those with an Unwrap method, like roles.Calc,
+// placement.Calc and multimode.Calc) update state of their own on every
+// match, so with them the processor rates one match at a time.
+type Processor struct {
+	Calc     skills.Calc
+	GameInfo *skills.GameInfo
+	Store    skills.RatingStore
+
+	Workers int // Number of matches rated at once (default runtime.GOMAXPROCS(0))
+	Window  int // Number of matches read ahead of the oldest unfinished one (default 1024 per worker)
+}
+
+// Metrics describe a run of a processor.
+type Metrics struct {
+	Matches     int           // Number of matches rated
+	Elapsed     time.Duration // Wall time of the run
+	Waited      int           // Number of matches that had to wait for an earlier match to finish
+	MaxInFlight int           // Most matches being rated at the same time
+}
+
+// Returns the number of matches rated per second.
+func (m Metrics) Throughput() float64 {
+	return float64(m.Matches) / m.Elapsed.Seconds()
+}
+
+func (m Metrics) String() string {
+	return fmt.Sprintf("%d matches in %v (%.0f/s), %d waited, at most %d in flight",
+		m.Matches, m.Elapsed, m.Throughput(), m.Waited, m.MaxInFlight)
+}
+
+// A calculator wrapping another one, keeping state of its own.
+type wrapper interface {
+	Unwrap() skills.Calc
+}
+
+type job struct {
+	index int
+	entry *matchlog.Entry
+	teams []skills.Team
+
+	waitingOn  int    // Number of unfinished earlier matches sharing a player
+	dependents []*job // Later matches waiting on this one
+
+	ratings skills.PlayerRatings
+	err     error
+}
+
+// Run rates every match of src and returns metrics of the run. It stops at
+// the first error from src or from rating a match: matches already being
+// rated are finished and stored, but no further matches are started.
+func (p *Processor) Run(src Source) (Metrics, error) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if _, ok := p.Calc.(wrapper); ok {
+		workers = 1
+	}
+	window := p.Window
+	if window <= 0 {
+		window = 1024 * workers
+	}
+
+	var m Metrics
+	start := time.Now()
+
+	jobs := make(chan *job)
+	results := make(chan *job, workers)
+	for i := 0; i < workers; i++ {
+		go p.work(jobs, results)
+	}
+	defer close(jobs)
+
+	// The last unfinished match each player is in
+	last := make(map[skills.Player]*job)
+
+	var ready []*job
+	var err error
+	pending, inFlight, read := 0, 0, 0
+	eof := false
+
+	for {
+		for err == nil && !eof && pending < window {
+			var e *matchlog.Entry
+			if e, err = src.Read(); err == io.EOF {
+				err, eof = nil, true
+				break
+			} else if err != nil {
+				break
+			}
+
+			j := &job{index: read, entry: e}
+			read++
+			pending++
+			seen := make(map[*job]bool)
+			for _, pl := range e.Players() {
+				if dep := last[pl]; dep != nil && !seen[dep] {
+					seen[dep] = true
+					dep.dependents = append(dep.dependents, j)
+					j.waitingOn++
+				}
+				last[pl] = j
+			}
+			if j.waitingOn == 0 {
+				ready = append(ready, j)
+			} else {
+				m.Waited++
+			}
+		}
+
+		if pending == 0 || (err != nil && inFlight == 0) {
+			break
+		}
+
+		var send chan *job
+		var next *job
+		if err == nil && len(ready) > 0 {
+			next = ready[0]
+			next.teams = next.entry.SkillTeams(p.GameInfo, p.Store)
+			send = jobs
+		}
+
+		select {
+		case send <- next:
+			ready = ready[1:]
+			inFlight++
+			if inFlight > m.MaxInFlight {
+				m.MaxInFlight = inFlight
+			}
+
+		case j := <-results:
+			inFlight--
+			pending--
+			if j.err != nil {
+				if err == nil {
+					err = fmt.Errorf("match %d: %v", j.index, j.err)
+				}
+				continue
+			}
+
+			for pl, r := range j.ratings {
+				p.Store.SetRating(pl, r)
+			}
+			for _, pl := range j.entry.Players() {
+				if last[pl] == j {
+					delete(last, pl)
+				}
+			}
+			for _, d := range j.dependents {
+				if d.waitingOn--; d.waitingOn == 0 {
+					ready = append(ready, d)
+				}
+			}
+			m.Matches++
+		}
+	}
+
+	m.Elapsed = time.Since(start)
+	return m, err
+}
+
+func (p *Processor) work(jobs <-chan *job, results chan<- *job) {
+	for j := range jobs {
+		p.rate(j)
+		results <- j
+	}
+}
+
+func (p *Processor) rate(j *job) {
+	// The calculators panic on invalid matches
+	defer func() {
+		if r := recover(); r != nil {
+			j.err = fmt.Errorf("%v", r)
+		}
+	}()
+	j.ratings = matchlog.Rate(p.Calc, p.GameInfo, j.entry, j.teams)
+}
@@ -0,0 +1,137 @@
+package batch
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"github.com/ChrisHines/GoSkills/skills/placement"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+type sliceSource []*matchlog.Entry
+
+func (s *sliceSource) Read() (*matchlog.Entry, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	e := (*s)[0]
+	*s = (*s)[1:]
+	return e, nil
+}
+
+// Generates matches between random players with teams of one to three players.
+func randomEntries(players, matches int) []*matchlog.Entry {
+	rnd := rand.New(rand.NewSource(1))
+	start := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var es []*matchlog.Entry
+	for i := 0; i < matches; i++ {
+		size := 1 + rnd.Intn(3)
+		picked := make(map[int]bool)
+		e := &matchlog.Entry{
+			Time:  start.Add(time.Duration(i) * time.Minute),
+			Teams: [][]string{{}, {}},
+			Ranks: []int{1, 1 + rnd.Intn(2)},
+		}
+		for j := 0; j < 2*size; j++ {
+			pl := rnd.Intn(players)
+			for picked[pl] {
+				pl = rnd.Intn(players)
+			}
+			picked[pl] = true
+			e.Teams[j%2] = append(e.Teams[j%2], fmt.Sprint(pl))
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+func TestProcessorMatchesSequential(t *testing.T) {
+	entries := randomEntries(60, 3000)
+	gi := *skills.DefaultGameInfo
+	gi.InactivityRate = 0.5
+
+	want := matchlog.Replay(&trueskill.TwoTeamCalc{}, &gi, entries, nil, nil)
+
+	for _, window := range []int{0, 1, 7} {
+		got := make(skills.PlayerRatings)
+		src := sliceSource(entries)
+		p := &Processor{Calc: &trueskill.TwoTeamCalc{}, GameInfo: &gi, Store: got, Workers: 8, Window: window}
+
+		m, err := p.Run(&src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Matches != len(entries) {
+			t.Errorf("window %v: Matches = %v, want %v", window, m.Matches, len(entries))
+		}
+		if len(got) != len(want) {
+			t.Errorf("window %v: rated %v players, want %v", window, len(got), len(want))
+		}
+		for pl, r := range want {
+			if !got[pl].Equal(r) {
+				t.Errorf("window %v: rating of %v = %v, want %v", window, pl, got[pl], r)
+			}
+		}
+	}
+}
+
+func TestProcessorStatefulCalc(t *testing.T) {
+	entries := randomEntries(30, 500)
+	gi := skills.DefaultGameInfo
+
+	// Every player starts in placement, whose state changes with every match
+	newCalc := func() *placement.Calc {
+		calc := placement.NewCalc(&trueskill.TwoTeamCalc{}, 4*gi.DynamicsFactor, 3, nil)
+		for i := 0; i < 30; i++ {
+			calc.Begin(*skills.NewPlayer(fmt.Sprint(i)))
+		}
+		return calc
+	}
+	want := matchlog.Replay(newCalc(), gi, entries, nil, nil)
+
+	got := make(skills.PlayerRatings)
+	src := sliceSource(entries)
+	p := &Processor{Calc: newCalc(), GameInfo: gi, Store: got, Workers: 8}
+	if _, err := p.Run(&src); err != nil {
+		t.Fatal(err)
+	}
+	for pl, r := range want {
+		if !got[pl].Equal(r) {
+			t.Errorf("rating of %v = %v, want %v", pl, got[pl], r)
+		}
+	}
+}
+
+func TestProcessorError(t *testing.T) {
+	entries := randomEntries(10, 20)
+	entries[5] = &matchlog.Entry{Teams: [][]string{{"a"}, {"b"}, {"c"}}, Ranks: []int{1, 2, 3}}
+
+	src := sliceSource(entries)
+	p := &Processor{Calc: &trueskill.TwoTeamCalc{}, GameInfo: skills.DefaultGameInfo, Store: make(skills.PlayerRatings), Workers: 2}
+	m, err := p.Run(&src)
+	if err == nil {
+		t.Fatalf("Run succeeded with an invalid match")
+	}
+	if m.Matches >= len(entries) {
+		t.Errorf("Matches = %v, want fewer than %v", m.Matches, len(entries))
+	}
+}
+
+func BenchmarkProcessor(b *testing.B) {
+	entries := randomEntries(100000, 100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		src := sliceSource(entries)
+		p := &Processor{Calc: &trueskill.TwoTeamCalc{}, GameInfo: skills.DefaultGameInfo, Store: make(skills.PlayerRatings)}
+		m, err := p.Run(&src)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportMetric(m.Throughput(), "matches/s")
+	}
+}
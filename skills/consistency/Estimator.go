@@ -0,0 +1,116 @@
+// Package consistency learns how consistently each player performs.
+//
+// GameInfo.Beta is the same performance noise for everyone, but some players
+// are far more volatile than others. An Estimator watches each player's
+// results and compares how surprising they were (the negative log
+// probability of the outcome) to how surprising they were expected to be
+// (the entropy of the predicted outcome). Players whose results keep
+// surprising more than expected get a larger beta and those who keep
+// surprising less get a smaller one, until the two balance. Calculators
+// pick the estimates up from the ratings' beta (see skills.Rating.WithBeta).
+package consistency
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"math"
+)
+
+// An Estimator learns a beta for each player from the surprise of their results.
+type Estimator struct {
+	Predictor skills.OutcomePredictor
+
+	// The step size of the update of the logarithm of a player's beta
+	// scale per match, per nat of excess surprise.
+	Rate float64
+
+	// The bounds of a player's beta as a multiple of GameInfo.Beta; 0 means
+	// no bound.
+	MinScale float64
+	MaxScale float64
+
+	// The logarithms of the players' beta scales
+	logScales map[skills.Player]float64
+}
+
+// Creates an estimator that predicts outcomes with the given predictor.
+func NewEstimator(p skills.OutcomePredictor) *Estimator {
+	return &Estimator{
+		Predictor: p,
+		Rate:      0.05,
+		MinScale:  0.5,
+		MaxScale:  2,
+		logScales: make(map[skills.Player]float64),
+	}
+}
+
+// Returns a player's beta as a multiple of GameInfo.Beta; 1 for players not
+// observed yet.
+func (e *Estimator) Scale(p skills.Player) float64 {
+	return math.Exp(e.logScales[p])
+}
+
+// Returns a player's estimated beta.
+func (e *Estimator) Beta(gi *skills.GameInfo, p skills.Player) float64 {
+	return gi.Beta * e.Scale(p)
+}
+
+// Returns copies of the teams with each rating carrying the player's
+// estimated beta.
+func (e *Estimator) Teams(gi *skills.GameInfo, teams []skills.Team) []skills.Team {
+	bteams := make([]skills.Team, len(teams))
+	for i, t := range teams {
+		bteams[i] = t.MapRatings(func(p skills.Player, r skills.Rating) skills.Rating {
+			return r.WithBeta(e.Beta(gi, p))
+		})
+	}
+	return bteams
+}
+
+// Observe updates the estimates of the players of a two team match from its
+// result. It should be called with the ratings the match is rated with,
+// before they are updated. Every player of the match shares in its surprise,
+// which is returned along with the expected surprise, in nats. Results the
+// predictor deems impossible, such as draws in a game without them, count as
+// having probability matchlog.MinProb.
+func (e *Estimator) Observe(gi *skills.GameInfo, teams []skills.Team, ranks ...int) (surprise, entropy float64) {
+	if len(teams) != 2 || len(ranks) != 2 {
+		panic(fmt.Errorf("%v teams and %v ranks, want 2 of each", len(teams), len(ranks)))
+	}
+
+	win, draw, lose := e.Predictor.CalcOutcomeProbs(gi, e.Teams(gi, teams))
+
+	p := draw
+	switch {
+	case ranks[0] < ranks[1]:
+		p = win
+	case ranks[0] > ranks[1]:
+		p = lose
+	}
+
+	surprise = -math.Log(math.Max(p, matchlog.MinProb))
+	for _, q := range []float64{win, draw, lose} {
+		if q > 0 {
+			entropy -= q * math.Log(q)
+		}
+	}
+
+	if e.logScales == nil {
+		e.logScales = make(map[skills.Player]float64)
+	}
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if e.MinScale > 0 {
+		lo = math.Log(e.MinScale)
+	}
+	if e.MaxScale > 0 {
+		hi = math.Log(e.MaxScale)
+	}
+	for _, t := range teams {
+		for _, pl := range t.Players() {
+			ls := e.logScales[pl] + e.Rate*(surprise-entropy)
+			e.logScales[pl] = math.Max(lo, math.Min(hi, ls))
+		}
+	}
+	return
+}
@@ -0,0 +1,109 @@
+package consistency
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestEstimator(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	calc := &trueskill.TwoPlayerCalc{}
+	e := NewEstimator(calc)
+
+	// Players with skills spread over the scale; the even ones perform
+	// steadily and the odd ones wildly
+	const n = 20
+	players := make([]skills.Player, n)
+	skill := make([]float64, n)
+	beta := make([]float64, n)
+	ratings := make(skills.PlayerRatings)
+	for i := range players {
+		players[i] = *skills.NewPlayer(i)
+		skill[i] = 10 + 30*float64(i)/n
+		beta[i] = 1
+		if i%2 == 1 {
+			beta[i] = 12
+		}
+		ratings[players[i]] = gi.DefaultRating()
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for k := 0; k < 20000; k++ {
+		i, j := rnd.Intn(n), rnd.Intn(n)
+		if i == j {
+			continue
+		}
+		team1 := skills.NewTeam()
+		team1.AddPlayer(players[i], ratings[players[i]])
+		team2 := skills.NewTeam()
+		team2.AddPlayer(players[j], ratings[players[j]])
+		teams := []skills.Team{team1, team2}
+
+		ranks := []int{1, 2}
+		if skill[i]+beta[i]*rnd.NormFloat64() < skill[j]+beta[j]*rnd.NormFloat64() {
+			ranks = []int{2, 1}
+		}
+
+		e.Observe(gi, teams, ranks...)
+		for p, r := range calc.CalcNewRatings(gi, e.Teams(gi, teams), ranks...) {
+			ratings[p] = r
+		}
+	}
+
+	var steady, wild float64
+	for i, p := range players {
+		s := e.Scale(p)
+		if s < e.MinScale || s > e.MaxScale {
+			t.Errorf("scale of player %v = %v, want within [%v, %v]", i, s, e.MinScale, e.MaxScale)
+		}
+		if r := ratings[p]; r.Beta() != e.Beta(gi, p) {
+			t.Errorf("rating of player %v has beta %v, want %v", i, r.Beta(), e.Beta(gi, p))
+		}
+		if i%2 == 1 {
+			wild += s / (n / 2)
+		} else {
+			steady += s / (n / 2)
+		}
+	}
+	if wild <= 1.2*steady {
+		t.Errorf("mean scale of wild players = %v, want well above that of steady players %v", wild, steady)
+	}
+	t.Logf("mean scale: steady %.3f, wild %.3f", steady, wild)
+}
+
+func TestImpossibleResult(t *testing.T) {
+	gi := *skills.DefaultGameInfo
+	gi.DrawProbability = 0
+	e := NewEstimator(&trueskill.TwoPlayerCalc{})
+
+	ann := *skills.NewPlayer("ann")
+	team1 := skills.NewTeam()
+	team1.AddPlayer(ann, gi.DefaultRating())
+	team2 := skills.NewTeam()
+	team2.AddPlayer(*skills.NewPlayer("bob"), gi.DefaultRating())
+
+	surprise, _ := e.Observe(&gi, []skills.Team{team1, team2}, 1, 1)
+	if math.IsInf(surprise, 0) || math.IsNaN(surprise) || math.IsNaN(e.Scale(ann)) || e.Scale(ann) != e.MaxScale {
+		t.Errorf("surprise of an impossible draw = %v, scale %v, want finite and the largest scale", surprise, e.Scale(ann))
+	}
+}
+
+func TestEstimatorLiteral(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	e := &Estimator{Predictor: &trueskill.TwoPlayerCalc{}, Rate: 0.05}
+
+	ann := *skills.NewPlayer("ann")
+	team1 := skills.NewTeam()
+	team1.AddPlayer(ann, skills.NewRating(10, 1))
+	team2 := skills.NewTeam()
+	team2.AddPlayer(*skills.NewPlayer("bob"), skills.NewRating(40, 1))
+
+	// Without bounds an upset raises the scale as far as it goes
+	surprise, entropy := e.Observe(gi, []skills.Team{team1, team2}, 1, 2)
+	if want := math.Exp(0.05 * (surprise - entropy)); math.Abs(e.Scale(ann)-want) > 1e-12 {
+		t.Errorf("scale after an upset = %v, want %v", e.Scale(ann), want)
+	}
+}
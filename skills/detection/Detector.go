@@ -0,0 +1,231 @@
+// Package detection flags suspicious results in a stream of rated matches.
+//
+// Every two team match is scored by the probability of its result under the
+// players' ratings going into it. Results less likely than a threshold are
+// improbable. Repeated improbable losses of one player to another in one on
+// one matches point to win trading, and new accounts that keep winning improbably point to
+// smurfs: experienced players on fresh accounts.
+package detection
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"sort"
+	"time"
+)
+
+// The kinds of flags.
+const (
+	Improbable = iota
+	WinTrading
+	Smurf
+)
+
+var kindNames = []string{"improbable result", "win trading", "smurf"}
+
+// A suspicious result or pattern of results.
+type Flag struct {
+	Kind int
+	Time time.Time // The time of the match raising the flag
+
+	// The players flagged: for an improbable result the winners then the
+	// losers (or everyone for a draw), for win trading the loser then the
+	// winner, and for a smurf the new account.
+	Players []skills.Player
+
+	// The probability of the result raising the flag.
+	Prob float64
+}
+
+func (f Flag) String() string {
+	kind := fmt.Sprintf("kind %d", f.Kind)
+	if f.Kind >= 0 && f.Kind < len(kindNames) {
+		kind = kindNames[f.Kind]
+	}
+	return fmt.Sprintf("%v %v: %v (p=%.4f)", f.Time.Format(time.RFC3339), kind, f.Players, f.Prob)
+}
+
+// A Detector watches two team matches for suspicious results. Matches with
+// other numbers of teams, and matches that can not be rated or predicted,
+// only count toward the players' match counts.
+type Detector struct {
+	Predictor skills.OutcomePredictor
+	GameInfo  *skills.GameInfo
+
+	// Results less likely than this are improbable.
+	ImprobableProb float64
+
+	// A player is flagged for win trading with another once they have lost
+	// improbably to them this many times in one on one matches. In team
+	// matches a loss can not be pinned on one pair of players.
+	TradeCount int
+
+	// Players are new accounts for their first NewAccountMatches matches,
+	// and are flagged as smurfs once they have won improbably SmurfWins
+	// times among them.
+	NewAccountMatches int
+	SmurfWins         int
+
+	// The flags raised so far, in the order of the matches.
+	Flags []Flag
+
+	// The number of matches skipped because they could not be rated or
+	// their result could not be predicted.
+	Skipped int
+
+	matches   map[skills.Player]int
+	losses    map[[2]skills.Player]int // Improbable losses of the first player to the second
+	smurfWins map[skills.Player]int
+	flagged   map[[2]skills.Player]bool // Pairs and (with themselves) smurfs already flagged
+}
+
+// Creates a detector predicting outcomes with the given predictor.
+func NewDetector(p skills.OutcomePredictor, gi *skills.GameInfo) *Detector {
+	d := &Detector{
+		Predictor:         p,
+		GameInfo:          gi,
+		ImprobableProb:    0.05,
+		TradeCount:        3,
+		NewAccountMatches: 20,
+		SmurfWins:         3,
+	}
+	d.init()
+	return d
+}
+
+// Creates the maps of a detector made without NewDetector.
+func (d *Detector) init() {
+	if d.matches == nil {
+		d.matches = make(map[skills.Player]int)
+		d.losses = make(map[[2]skills.Player]int)
+		d.smurfWins = make(map[skills.Player]int)
+		d.flagged = make(map[[2]skills.Player]bool)
+	}
+}
+
+// Records the number of matches a player has played, e.g. for players with a
+// history from before the stream.
+func (d *Detector) SetMatchCount(p skills.Player, n int) {
+	d.init()
+	d.matches[p] = n
+}
+
+// Visit examines a match before it is rated, with the teams holding the
+// players' ratings going into it. Its signature makes it usable as a
+// matchlog.Visitor.
+func (d *Detector) Visit(e *matchlog.Entry, teams []skills.Team) {
+	d.Observe(e, teams)
+}
+
+// Observe is Visit returning the flags raised by the match.
+func (d *Detector) Observe(e *matchlog.Entry, teams []skills.Team) []Flag {
+	d.init()
+	defer func() {
+		for _, p := range e.Players() {
+			d.matches[p]++
+		}
+	}()
+	if len(teams) != 2 {
+		return nil
+	}
+
+	win, draw, lose, ok := d.predict(teams)
+	if !ok {
+		d.Skipped++
+		return nil
+	}
+	winners, losers, prob := teams[0].Players(), teams[1].Players(), win
+	switch {
+	case e.Ranks[0] == e.Ranks[1]:
+		prob = draw
+	case e.Ranks[1] < e.Ranks[0]:
+		winners, losers, prob = losers, winners, lose
+	}
+	if prob >= d.ImprobableProb {
+		return nil
+	}
+
+	n := len(d.Flags)
+	d.Flags = append(d.Flags, Flag{Improbable, e.Time, append(winners, losers...), prob})
+	if e.Ranks[0] != e.Ranks[1] {
+		if len(winners) == 1 && len(losers) == 1 {
+			pair := [2]skills.Player{losers[0], winners[0]}
+			d.losses[pair]++
+			if d.losses[pair] >= d.TradeCount && !d.flagged[pair] {
+				d.flagged[pair] = true
+				d.Flags = append(d.Flags, Flag{WinTrading, e.Time, []skills.Player{losers[0], winners[0]}, prob})
+			}
+		}
+		for _, w := range winners {
+			if d.matches[w] >= d.NewAccountMatches {
+				continue
+			}
+			d.smurfWins[w]++
+			if self := [2]skills.Player{w, w}; d.smurfWins[w] >= d.SmurfWins && !d.flagged[self] {
+				d.flagged[self] = true
+				d.Flags = append(d.Flags, Flag{Smurf, e.Time, []skills.Player{w}, prob})
+			}
+		}
+	}
+	return d.Flags[n:]
+}
+
+// Returns the predicted outcome probabilities of a match, or false if the
+// predictor can not predict it.
+func (d *Detector) predict(teams []skills.Team) (win, draw, lose float64, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	win, draw, lose = d.Predictor.CalcOutcomeProbs(d.GameInfo, teams)
+	return win, draw, lose, true
+}
+
+// Run rates the matches of the source in order with matchlog.ReplaySource,
+// updating the ratings in place, and examines each one before it is rated.
+// If ratings is nil every player starts with the default rating. Matches the
+// calculator can not rate (see matchlog.Check) are skipped rather than
+// rated.
+func (d *Detector) Run(src matchlog.Source, calc skills.Calc, ratings skills.PlayerRatings) (skills.PlayerRatings, error) {
+	d.init()
+	return matchlog.ReplaySource(calc, d.GameInfo, &checkedSource{src, calc, d}, ratings, d.Visit)
+}
+
+// A source passing on the entries of another that the calculator can rate,
+// and skipping the others for the detector.
+type checkedSource struct {
+	src  matchlog.Source
+	calc skills.Calc
+	d    *Detector
+}
+
+func (s *checkedSource) Read() (*matchlog.Entry, error) {
+	for {
+		e, err := s.src.Read()
+		if err != nil || matchlog.Check(s.calc, s.d.GameInfo, e) == nil {
+			return e, err
+		}
+		s.d.Skipped++
+		for _, p := range e.Players() {
+			s.d.matches[p]++
+		}
+	}
+}
+
+// Returns the players flagged with the given kind of flag, sorted by id.
+func (d *Detector) Report(kind int) []skills.Player {
+	seen := make(map[skills.Player]bool)
+	ps := []skills.Player{}
+	for _, f := range d.Flags {
+		for _, p := range f.Players {
+			if f.Kind == kind && !seen[p] {
+				seen[p] = true
+				ps = append(ps, p)
+			}
+		}
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].String() < ps[j].String() })
+	return ps
+}
@@ -0,0 +1,109 @@
+package detection
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Bob keeps beating the much stronger ann, and the new account kid beats
+// three ever stronger aces in a row
+const testLog = `{"time":"2013-04-01T18:00:00Z","teams":[["ann"],["bob"]],"ranks":[2,1]}
+{"time":"2013-04-01T18:10:00Z","teams":[["ann"],["cat"]],"ranks":[1,2]}
+{"time":"2013-04-01T18:20:00Z","teams":[["bob"],["ann"]],"ranks":[1,2]}
+{"time":"2013-04-01T18:30:00Z","teams":[["ann"],["bob"]],"ranks":[2,1]}
+{"time":"2013-04-01T19:00:00Z","teams":[["kid"],["ace1"]],"ranks":[1,2]}
+{"time":"2013-04-01T19:10:00Z","teams":[["ace2"],["kid"]],"ranks":[2,1]}
+{"time":"2013-04-01T19:20:00Z","teams":[["kid"],["ace3"]],"ranks":[1,2]}
+`
+
+func TestDetector(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	calc := &trueskill.TwoTeamCalc{}
+	d := NewDetector(calc, gi)
+
+	ratings := skills.PlayerRatings{}
+	for id, mean := range map[string]float64{"ann": 40, "bob": 20, "cat": 30, "ace1": 60, "ace2": 80, "ace3": 100} {
+		p := *skills.NewPlayer(id)
+		ratings[p] = skills.NewRating(mean, 1)
+		d.SetMatchCount(p, 100)
+	}
+
+	if _, err := d.Run(matchlog.NewReader(strings.NewReader(testLog)), calc, ratings); err != nil {
+		t.Fatal(err)
+	}
+	ids := func(ps []skills.Player) []string {
+		s := []string{}
+		for _, p := range ps {
+			s = append(s, p.String())
+		}
+		return s
+	}
+	if got, want := ids(d.Report(Improbable)), []string{"ace1", "ace2", "ace3", "ann", "bob", "kid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("improbable results by %v, want %v", got, want)
+	}
+	if got, want := ids(d.Report(WinTrading)), []string{"ann", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("win trading by %v, want %v", got, want)
+	}
+	if got, want := ids(d.Report(Smurf)), []string{"kid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("smurfs %v, want %v", got, want)
+	}
+
+	// A pair is flagged for win trading only once
+	e := &matchlog.Entry{Teams: [][]string{{"ann"}, {"bob"}}, Ranks: []int{2, 1}}
+	flags := d.Observe(e, e.SkillTeams(gi, ratings))
+	if len(flags) != 1 || flags[0].Kind != Improbable {
+		t.Errorf("flags of bob's fourth win = %v, want only an improbable result", flags)
+	}
+}
+
+func TestDetectorTeams(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	calc := &trueskill.TwoTeamCalc{}
+
+	// A literal works, and the weaker team's repeated wins flag no pair for
+	// win trading
+	d := &Detector{Predictor: calc, GameInfo: gi, ImprobableProb: 0.05, TradeCount: 2}
+	ratings := skills.PlayerRatings{}
+	for id, mean := range map[string]float64{"ann": 40, "bob": 40, "cat": 20, "dan": 20} {
+		ratings[*skills.NewPlayer(id)] = skills.NewRating(mean, 1)
+	}
+	e := &matchlog.Entry{Teams: [][]string{{"ann", "bob"}, {"cat", "dan"}}, Ranks: []int{2, 1}}
+	for i := 0; i < 3; i++ {
+		d.Observe(e, e.SkillTeams(gi, ratings))
+	}
+	if got := d.Report(WinTrading); len(got) != 0 {
+		t.Errorf("win trading in team matches by %v, want none", got)
+	}
+	if got := d.Report(Improbable); len(got) != 4 {
+		t.Errorf("improbable results by %v, want all four players", got)
+	}
+
+	if s := (Flag{Kind: len(kindNames)}).String(); !strings.Contains(s, "kind") {
+		t.Errorf("flag of unknown kind = %q", s)
+	}
+}
+
+func TestDetectorSkips(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	d := NewDetector(&trueskill.TwoPlayerCalc{}, gi)
+
+	// The three team match can not be rated and the two on one can not be
+	// predicted, but both count toward the match counts
+	log := `{"teams":[["ann"],["bob"],["cat"]],"ranks":[1,2,3]}
+{"teams":[["ann"],["bob"]],"ranks":[1,2]}
+`
+	if _, err := d.Run(matchlog.NewReader(strings.NewReader(log)), &trueskill.TwoTeamCalc{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	e := &matchlog.Entry{Teams: [][]string{{"ann", "cat"}, {"bob"}}, Ranks: []int{1, 2}}
+	if flags := d.Observe(e, e.SkillTeams(gi, nil)); len(flags) != 0 {
+		t.Errorf("flags of an unpredictable match = %v", flags)
+	}
+	if ann := *skills.NewPlayer("ann"); d.Skipped != 2 || d.matches[ann] != 3 {
+		t.Errorf("%v skipped and ann counted in %v matches, want 2 and 3", d.Skipped, d.matches[ann])
+	}
+}
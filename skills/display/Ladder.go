@@ -0,0 +1,179 @@
+package display
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"sort"
+)
+
+// A named tier starting at a display value. The span up to the next tier
+// (or the end of the scale) is split evenly into divisions.
+type Tier struct {
+	Name      string
+	Min       float64
+	Divisions int // 0 is the same as 1
+}
+
+// A Ladder divides a scale into tiers and divisions.
+type Ladder struct {
+	Scale *Scale
+	Tiers []Tier // In increasing order of Min
+
+	// How far, in display units, a value must move past a boundary before
+	// a player is promoted or demoted across it. This keeps players near a
+	// boundary from flickering between placements.
+	Hysteresis float64
+}
+
+// A player's place on a ladder. Division 0 is the lowest division of a tier.
+type Placement struct {
+	Tier     int
+	Division int
+}
+
+// Returns whether p is ranked above q.
+func (p Placement) Above(q Placement) bool {
+	return p.Tier > q.Tier || p.Tier == q.Tier && p.Division > q.Division
+}
+
+// Returns the ladder's steps, from the lowest division of the lowest tier to
+// the highest division of the highest tier, and the display value each starts at.
+func (l *Ladder) build() (steps []Placement, mins []float64) {
+	for i, t := range l.Tiers {
+		hi := l.Scale.Max
+		if i+1 < len(l.Tiers) {
+			hi = l.Tiers[i+1].Min
+		}
+		n := t.Divisions
+		if n < 1 {
+			n = 1
+		}
+		for d := 0; d < n; d++ {
+			steps = append(steps, Placement{i, d})
+			mins = append(mins, t.Min+float64(d)*(hi-t.Min)/float64(n))
+		}
+	}
+	return
+}
+
+func step(steps []Placement, p Placement) int {
+	return sort.Search(len(steps), func(i int) bool { return !p.Above(steps[i]) })
+}
+
+// Returns the placement of a rating, ignoring hysteresis. A ladder without
+// tiers places every rating at the zero Placement.
+func (l *Ladder) Place(r skills.Rating) Placement {
+	steps, mins := l.build()
+	if len(steps) == 0 {
+		return Placement{}
+	}
+	v := l.Scale.Value(r)
+	i := sort.Search(len(mins), func(i int) bool { return mins[i] > v }) - 1
+	if i < 0 {
+		i = 0
+	}
+	return steps[i]
+}
+
+// Returns the placement of a rating for a player currently placed at cur.
+// The player only moves once the rating is more than Hysteresis past the
+// boundary of the new placement. Placements above the top of the ladder,
+// e.g. restored after tiers were removed, count as its top step.
+func (l *Ladder) Move(cur Placement, r skills.Rating) Placement {
+	steps, mins := l.build()
+	if len(steps) == 0 {
+		return Placement{}
+	}
+	v := l.Scale.Value(r)
+	c := step(steps, cur)
+	if c >= len(steps) {
+		c = len(steps) - 1
+	}
+
+	i := c
+	for i+1 < len(steps) && v >= mins[i+1]+l.Hysteresis {
+		i++
+	}
+	if i == c {
+		for i > 0 && v < mins[i]-l.Hysteresis {
+			i--
+		}
+	}
+	return steps[i]
+}
+
+// Returns the name of a placement, e.g. "Gold 2". Divisions are numbered
+// from the top, so division 1 is the highest division of a tier. Placements
+// outside the ladder have no name.
+func (l *Ladder) Name(p Placement) string {
+	if p.Tier < 0 || p.Tier >= len(l.Tiers) {
+		return ""
+	}
+	t := l.Tiers[p.Tier]
+	if t.Divisions <= 1 {
+		return t.Name
+	}
+	return fmt.Sprintf("%v %d", t.Name, t.Divisions-p.Division)
+}
+
+// A change of a player's placement.
+type Event struct {
+	Player   skills.Player
+	From, To Placement
+}
+
+// Returns whether the event is a promotion rather than a demotion.
+func (e Event) Promotion() bool {
+	return e.To.Above(e.From)
+}
+
+// A Tracker remembers each player's placement on a ladder so hysteresis can
+// be applied across matches.
+type Tracker struct {
+	Ladder     *Ladder
+	placements map[skills.Player]Placement
+}
+
+func NewTracker(l *Ladder) *Tracker {
+	return &Tracker{l, make(map[skills.Player]Placement)}
+}
+
+// Returns the current placement of a player and whether it is known.
+func (t *Tracker) Placement(p skills.Player) (Placement, bool) {
+	pl, ok := t.placements[p]
+	return pl, ok
+}
+
+// Records a player's placement, e.g. when restoring a tracker.
+func (t *Tracker) SetPlacement(p skills.Player, pl Placement) {
+	t.placements[p] = pl
+}
+
+// Update applies the result of a CalcNewRatings call and returns an event for
+// every player whose placement changed, ordered by player. Players not yet
+// tracked are placed by their ratings in priors first.
+func (t *Tracker) Update(priors []skills.Team, newRatings skills.PlayerRatings) []Event {
+	for _, team := range priors {
+		for p, r := range team.PlayerRatings {
+			if _, ok := t.placements[p]; !ok {
+				t.placements[p] = t.Ladder.Place(r)
+			}
+		}
+	}
+
+	var events []Event
+	for p, r := range newRatings {
+		from, ok := t.placements[p]
+		if !ok {
+			t.placements[p] = t.Ladder.Place(r)
+			continue
+		}
+		if to := t.Ladder.Move(from, r); to != from {
+			t.placements[p] = to
+			events = append(events, Event{p, from, to})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Player.String() < events[j].Player.String() })
+	return events
+}
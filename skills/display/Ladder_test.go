@@ -0,0 +1,125 @@
+package display
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"testing"
+)
+
+var testLadder = &Ladder{
+	Scale: MMR,
+	Tiers: []Tier{
+		{Name: "Bronze", Min: 0, Divisions: 3},
+		{Name: "Silver", Min: 900, Divisions: 3},
+		{Name: "Gold", Min: 1800, Divisions: 2},
+		{Name: "Master", Min: 2700},
+	},
+	Hysteresis: 30,
+}
+
+// Returns a rating whose MMR is the given value.
+func mmr(v float64) skills.Rating {
+	return skills.NewRating(v/60+3, 1)
+}
+
+func TestScale(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	if v := Levels.Value(gi.DefaultRating()); v != 0 {
+		t.Errorf("level of a new player = %v, want %v", v, 0)
+	}
+	if v := MMR.Value(skills.NewRating(40, 5)); v != 1500 {
+		t.Errorf("MMR of {μ:40 σ:5} = %v, want %v", v, 1500)
+	}
+	if v := MMR.Value(skills.NewRating(80, 1)); v != 3000 {
+		t.Errorf("MMR of {μ:80 σ:1} = %v, want %v", v, 3000)
+	}
+	flat := &Scale{Lo: 10, Hi: 10, Min: 0, Max: 100}
+	if lo, hi := flat.Map(9), flat.Map(10); lo != 0 || hi != 100 {
+		t.Errorf("empty scale maps 9 to %v and 10 to %v, want 0 and 100", lo, hi)
+	}
+}
+
+func TestPlace(t *testing.T) {
+	for _, c := range []struct {
+		mmr  float64
+		name string
+	}{
+		{0, "Bronze 3"},
+		{299, "Bronze 3"},
+		{300, "Bronze 2"},
+		{1000, "Silver 3"},
+		{1799, "Silver 1"},
+		{2250, "Gold 1"},
+		{3000, "Master"},
+	} {
+		if name := testLadder.Name(testLadder.Place(mmr(c.mmr))); name != c.name {
+			t.Errorf("placement of MMR %v = %v, want %v", c.mmr, name, c.name)
+		}
+	}
+}
+
+func TestEmptyLadder(t *testing.T) {
+	empty := &Ladder{Scale: MMR}
+	if pl := empty.Place(mmr(1000)); pl != (Placement{}) || empty.Name(pl) != "" {
+		t.Errorf("placement on an empty ladder = %+v named %q", pl, empty.Name(pl))
+	}
+	if pl := empty.Move(Placement{}, mmr(1000)); pl != (Placement{}) {
+		t.Errorf("move on an empty ladder = %+v", pl)
+	}
+
+	// A placement restored after the top tiers were removed
+	short := &Ladder{Scale: MMR, Tiers: testLadder.Tiers[:1]}
+	if pl := short.Move(Placement{Tier: 3}, mmr(0)); pl.Tier != 0 {
+		t.Errorf("move from above the ladder = %+v", pl)
+	}
+}
+
+func TestMoveHysteresis(t *testing.T) {
+	silver3 := Placement{1, 0}
+	for _, c := range []struct {
+		mmr  float64
+		name string
+	}{
+		{1210, "Silver 3"}, // Past the boundary but within the hysteresis
+		{1230, "Silver 2"},
+		{1810, "Silver 1"},
+		{1900, "Gold 2"},
+		{880, "Silver 3"},
+		{869, "Bronze 1"},
+		{100, "Bronze 3"},
+	} {
+		if name := testLadder.Name(testLadder.Move(silver3, mmr(c.mmr))); name != c.name {
+			t.Errorf("Silver 3 moved with MMR %v = %v, want %v", c.mmr, name, c.name)
+		}
+	}
+}
+
+func TestTrackerEvents(t *testing.T) {
+	player1 := *skills.NewPlayer(1)
+	player2 := *skills.NewPlayer(2)
+
+	team1 := skills.NewTeam()
+	team1.AddPlayer(player1, mmr(1190))
+	team2 := skills.NewTeam()
+	team2.AddPlayer(player2, mmr(1210))
+	priors := []skills.Team{team1, team2}
+
+	tr := NewTracker(testLadder)
+	events := tr.Update(priors, skills.PlayerRatings{player1: mmr(1220), player2: mmr(1180)})
+	if len(events) != 0 {
+		t.Errorf("events within the hysteresis = %v, want none", events)
+	}
+
+	events = tr.Update(priors, skills.PlayerRatings{player1: mmr(1240), player2: mmr(1160)})
+	if len(events) != 2 {
+		t.Fatalf("events = %v, want 2", events)
+	}
+	if e := events[0]; e.Player != player1 || !e.Promotion() || testLadder.Name(e.To) != "Silver 2" {
+		t.Errorf("events[0] = %+v, want a promotion of player 1 to Silver 2", e)
+	}
+	if e := events[1]; e.Player != player2 || e.Promotion() || testLadder.Name(e.To) != "Silver 3" {
+		t.Errorf("events[1] = %+v, want a demotion of player 2 to Silver 3", e)
+	}
+	if pl, ok := tr.Placement(player2); !ok || pl != events[1].To {
+		t.Errorf("Placement(player 2) = %v, %v, want %v", pl, ok, events[1].To)
+	}
+}
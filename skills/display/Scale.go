@@ -0,0 +1,42 @@
+// Package display maps ratings onto the scales shown to players.
+//
+// Players see neither μ nor σ. A Scale turns the conservative rating
+// (μ - 3σ) into a display value such as a 0-50 level or a 0-3000 MMR, and a
+// Ladder divides a scale into named tiers and divisions.
+package display
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"math"
+)
+
+// A Scale maps conservative ratings linearly onto a display range. Ratings
+// outside [Lo, Hi] are clamped to the ends of the range. If Hi equals Lo,
+// ratings below Lo map to Min and the others to Max.
+type Scale struct {
+	Lo, Hi   float64 // The conservative ratings mapped to Min and Max
+	Min, Max float64 // The display range
+}
+
+// 0-50 levels. With the default game info a new player starts at level 0.
+var Levels = &Scale{Lo: 0, Hi: 50, Min: 0, Max: 50}
+
+// A 0-3000 matchmaking rating over the same span of conservative ratings as Levels.
+var MMR = &Scale{Lo: 0, Hi: 50, Min: 0, Max: 3000}
+
+// Returns the display value of a conservative rating.
+func (s *Scale) Map(conservative float64) float64 {
+	if s.Hi == s.Lo {
+		if conservative < s.Lo {
+			return s.Min
+		}
+		return s.Max
+	}
+	x := (conservative - s.Lo) / (s.Hi - s.Lo)
+	return s.Min + math.Min(math.Max(x, 0), 1)*(s.Max-s.Min)
+}
+
+// Returns the display value of a rating.
+func (s *Scale) Value(r skills.Rating) float64 {
+	return s.Map(r.ConservativeRating())
+}
@@ -0,0 +1,201 @@
+// Package eval measures how well a calculator predicts match outcomes.
+//
+// A match log is replayed through the calculator; every match is predicted
+// from the ratings before it was played and only then rated. The predicted
+// probabilities of a win, draw and loss for the first team are scored against
+// the actual result.
+package eval
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// The outcomes of a match for the first team.
+const (
+	Win = iota
+	Draw
+	Lose
+	numOutcomes
+)
+
+// One row of a reliability table: the predictions whose probability fell
+// in [Lo, Hi) and how often the predicted outcome happened.
+type Bin struct {
+	Lo, Hi    float64
+	Count     int
+	Predicted float64 // Sum of the predicted probabilities
+	Observed  int     // Number of predictions whose outcome happened
+}
+
+// Returns the mean predicted probability of the bin.
+func (b *Bin) MeanPredicted() float64 {
+	return b.Predicted / float64(b.Count)
+}
+
+// Returns the fraction of the bin's predictions whose outcome happened.
+func (b *Bin) ObservedFreq() float64 {
+	return float64(b.Observed) / float64(b.Count)
+}
+
+// Scores accumulated over a set of matches.
+type Stats struct {
+	Matches int
+	LogLoss float64 // Mean negative log probability of the actual outcome
+	Brier   float64 // Mean squared error of the outcome probabilities, summed over outcomes
+	Correct int     // Number of matches where the most likely outcome happened
+
+	// The reliability table. Each match contributes one prediction per
+	// outcome, so a well calibrated calculator has ObservedFreq close to
+	// MeanPredicted in every bin.
+	Calibration []Bin
+}
+
+func newStats(bins int) *Stats {
+	s := &Stats{Calibration: make([]Bin, bins)}
+	for i := range s.Calibration {
+		s.Calibration[i].Lo = float64(i) / float64(bins)
+		s.Calibration[i].Hi = float64(i+1) / float64(bins)
+	}
+	return s
+}
+
+// Returns the fraction of matches where the most likely outcome happened.
+func (s *Stats) Accuracy() float64 {
+	return float64(s.Correct) / float64(s.Matches)
+}
+
+func (s *Stats) add(probs [numOutcomes]float64, actual int) {
+	n := float64(s.Matches)
+	s.Matches++
+
+	logLoss := -math.Log(math.Max(probs[actual], matchlog.MinProb))
+	s.LogLoss += (logLoss - s.LogLoss) / (n + 1)
+
+	brier := 0.0
+	best := 0
+	for o, p := range probs {
+		hit := 0.0
+		if o == actual {
+			hit = 1
+		}
+		brier += (p - hit) * (p - hit)
+
+		if p > probs[best] {
+			best = o
+		}
+
+		b := &s.Calibration[int(math.Min(p*float64(len(s.Calibration)), float64(len(s.Calibration)-1)))]
+		b.Count++
+		b.Predicted += p
+		b.Observed += int(hit)
+	}
+	s.Brier += (brier - s.Brier) / (n + 1)
+
+	if best == actual {
+		s.Correct++
+	}
+}
+
+// The results of a backtest.
+type Report struct {
+	Overall    *Stats
+	ByMode     map[string]*Stats
+	ByTeamSize map[string]*Stats // Keyed by matchlog.Entry.TeamSize, e.g. "2v2"
+	Skipped    int               // Number of matches skipped because they did not have exactly two teams or the calculator could not rate them
+}
+
+// Backtest replays the entries through calc and scores its predictions. The
+// reliability tables have the given number of equal width bins.
+func Backtest(calc matchlog.Predictor, gi *skills.GameInfo, entries []*matchlog.Entry, bins int) *Report {
+	r := &Report{
+		Overall:    newStats(bins),
+		ByMode:     make(map[string]*Stats),
+		ByTeamSize: make(map[string]*Stats),
+	}
+
+	var two []*matchlog.Entry
+	two, r.Skipped = matchlog.TwoTeamEntries(calc, gi, entries)
+	matchlog.Replay(calc, gi, two, nil, func(e *matchlog.Entry, teams []skills.Team) {
+		var probs [numOutcomes]float64
+		probs[Win], probs[Draw], probs[Lose] = calc.CalcOutcomeProbs(gi, teams)
+
+		actual := Draw
+		switch e.Result() {
+		case skills.Win:
+			actual = Win
+		case skills.Lose:
+			actual = Lose
+		}
+
+		r.Overall.add(probs, actual)
+		group(r.ByMode, e.Mode, bins).add(probs, actual)
+		group(r.ByTeamSize, e.TeamSize(), bins).add(probs, actual)
+	})
+
+	return r
+}
+
+func group(m map[string]*Stats, key string, bins int) *Stats {
+	s, ok := m[key]
+	if !ok {
+		s = newStats(bins)
+		m[key] = s
+	}
+	return s
+}
+
+// Write prints the report as plain text tables.
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(tw, "\tmatches\tlog-loss\tbrier\taccuracy\t")
+	writeStats(tw, "overall", r.Overall)
+	for _, k := range sortedKeys(r.ByMode) {
+		name := k
+		if name == "" {
+			name = "(no mode)"
+		}
+		writeStats(tw, "mode "+name, r.ByMode[k])
+	}
+	for _, k := range sortedKeys(r.ByTeamSize) {
+		writeStats(tw, "size "+k, r.ByTeamSize[k])
+	}
+	if r.Skipped > 0 {
+		fmt.Fprintf(tw, "skipped\t%d\t\t\t\t\n", r.Skipped)
+	}
+
+	fmt.Fprintln(tw, "\t\t\t\t\t")
+	fmt.Fprintln(tw, "calibration\tcount\tpredicted\tobserved\t\t")
+	for i := range r.Overall.Calibration {
+		b := &r.Overall.Calibration[i]
+		if b.Count == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "[%.2f, %.2f)\t%d\t%.4f\t%.4f\t\t\n", b.Lo, b.Hi, b.Count, b.MeanPredicted(), b.ObservedFreq())
+	}
+
+	return tw.Flush()
+}
+
+func writeStats(w io.Writer, name string, s *Stats) {
+	if s.Matches == 0 {
+		fmt.Fprintf(w, "%v\t0\t\t\t\t\n", name)
+		return
+	}
+	fmt.Fprintf(w, "%v\t%d\t%.4f\t%.4f\t%.4f\t\n", name, s.Matches, s.LogLoss, s.Brier, s.Accuracy())
+}
+
+func sortedKeys(m map[string]*Stats) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
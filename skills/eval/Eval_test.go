@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"bytes"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testLog = `{"mode":"ranked","teams":[["ann"],["bob"]],"ranks":[1,2]}
+{"mode":"ranked","teams":[["ann"],["bob"]],"ranks":[1,2]}
+{"mode":"casual","teams":[["ann","cat"],["bob","dan"]],"ranks":[2,1]}
+{"mode":"casual","teams":[["ann"],["bob"],["cat"]],"ranks":[1,2,3]}
+`
+
+func TestBacktest(t *testing.T) {
+	entries, err := matchlog.NewReader(strings.NewReader(testLog)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gi := skills.DefaultGameInfo
+	r := Backtest(&trueskill.TwoTeamCalc{}, gi, entries, 10)
+
+	if r.Overall.Matches != 3 || r.Skipped != 1 {
+		t.Errorf("Matches, Skipped = %v, %v, want %v, %v", r.Overall.Matches, r.Skipped, 3, 1)
+	}
+	if n := r.ByMode["ranked"].Matches; n != 2 {
+		t.Errorf("ranked matches = %v, want %v", n, 2)
+	}
+	if n := r.ByTeamSize["2v2"].Matches; n != 1 {
+		t.Errorf("2v2 matches = %v, want %v", n, 1)
+	}
+
+	// The first match is between two new players, so it is predicted from
+	// default ratings; the second uses the ratings after the first.
+	teams := entries[0].SkillTeams(gi, nil)
+	win, draw, lose := (&trueskill.TwoTeamCalc{}).CalcOutcomeProbs(gi, teams)
+	first := Backtest(&trueskill.TwoTeamCalc{}, gi, entries[:1], 10).Overall
+	if want := -math.Log(win); math.Abs(first.LogLoss-want) > 1e-12 {
+		t.Errorf("log-loss = %v, want %v", first.LogLoss, want)
+	}
+	if want := (win-1)*(win-1) + draw*draw + lose*lose; math.Abs(first.Brier-want) > 1e-12 {
+		t.Errorf("Brier score = %v, want %v", first.Brier, want)
+	}
+	if r.ByMode["ranked"].LogLoss >= first.LogLoss {
+		t.Errorf("ranked log-loss = %v, want less than %v after learning from the first match", r.ByMode["ranked"].LogLoss, first.LogLoss)
+	}
+
+	count := 0
+	for _, b := range r.Overall.Calibration {
+		count += b.Count
+	}
+	if count != 3*numOutcomes {
+		t.Errorf("calibration count = %v, want %v", count, 3*numOutcomes)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"overall", "mode casual", "size 2v2", "skipped", "calibration"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report does not contain %q\n%v", want, buf.String())
+		}
+	}
+}
+
+func TestBacktestSkipsUnratableMatches(t *testing.T) {
+	entries, err := matchlog.NewReader(strings.NewReader(testLog)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TwoPlayerCalc can not rate the 2v2 match
+	r := Backtest(&trueskill.TwoPlayerCalc{}, skills.DefaultGameInfo, entries, 10)
+	if r.Overall.Matches != 2 || r.Skipped != 2 || r.ByTeamSize["2v2"] != nil {
+		t.Errorf("Matches, Skipped = %v, %v, want %v, %v", r.Overall.Matches, r.Skipped, 2, 2)
+	}
+}
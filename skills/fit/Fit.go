@@ -0,0 +1,155 @@
+// Package fit learns GameInfo parameters from a history of played matches.
+//
+// The fitted parameters are the ones that maximize the predictive
+// log-likelihood of the history: each match is predicted from the ratings
+// before it was played, then rated, in log order. Beta, DynamicsFactor,
+// DrawProbability and InitialStddev are searched; InitialMean only sets the
+// scale of the ratings and is kept as given.
+package fit
+
+import (
+	"errors"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"math"
+)
+
+// Options control the coordinate search. Zero values select the defaults.
+type Options struct {
+	InitialStep float64 // Starting step size in the transformed parameter space (default 0.5)
+	MinStep     float64 // The search stops once the step size falls below this (default 0.001)
+	MaxEvals    int     // The search stops after this many likelihood evaluations (default 1000)
+}
+
+var defaultOptions = Options{
+	InitialStep: 0.5,
+	MinStep:     0.001,
+	MaxEvals:    1000,
+}
+
+// The fitted parameters along with diagnostics of the fit.
+type Result struct {
+	GameInfo *skills.GameInfo
+
+	LogLikelihood        float64 // Log-likelihood of the history under the fitted parameters
+	InitialLogLikelihood float64 // Log-likelihood of the history under the starting parameters
+
+	Matches int // Number of matches that were predicted
+	Skipped int // Number of matches skipped because they did not have exactly two teams or the calculator could not rate them
+
+	Evals     int  // Number of likelihood evaluations
+	Sweeps    int  // Number of sweeps over all parameters
+	Converged bool // Whether the step size shrank below MinStep before MaxEvals was reached
+}
+
+// Returns the average negative log-likelihood per match of the fitted parameters.
+func (r *Result) LogLoss() float64 {
+	return -r.LogLikelihood / float64(r.Matches)
+}
+
+// Calculates the predictive log-likelihood of the entries. Only matches
+// between two teams that the calculator can rate are predicted and rated;
+// the number of predicted matches is returned as well.
+func LogLikelihood(calc matchlog.Predictor, gi *skills.GameInfo, entries []*matchlog.Entry) (ll float64, n int) {
+	entries, _ = matchlog.TwoTeamEntries(calc, gi, entries)
+	return logLikelihood(calc, gi, entries), len(entries)
+}
+
+func logLikelihood(calc matchlog.Predictor, gi *skills.GameInfo, entries []*matchlog.Entry) (ll float64) {
+	matchlog.Replay(calc, gi, entries, nil, func(e *matchlog.Entry, teams []skills.Team) {
+		ll += math.Log(e.ResultProb(calc.CalcOutcomeProbs(gi, teams)))
+	})
+	return
+}
+
+// Fits the GameInfo parameters to the entries by coordinate search, starting
+// from start. Each parameter is stepped in turn in a transformed space where
+// it is unbounded (log for the deviations, logit for the draw probability);
+// a step is kept if it improves the likelihood and the step size is halved
+// after a sweep without improvement. A nil opts selects the defaults.
+func Fit(calc matchlog.Predictor, start *skills.GameInfo, entries []*matchlog.Entry, opts *Options) (*Result, error) {
+	o := defaultOptions
+	if opts != nil {
+		if opts.InitialStep > 0 {
+			o.InitialStep = opts.InitialStep
+		}
+		if opts.MinStep > 0 {
+			o.MinStep = opts.MinStep
+		}
+		if opts.MaxEvals > 0 {
+			o.MaxEvals = opts.MaxEvals
+		}
+	}
+
+	entries, skipped := matchlog.TwoTeamEntries(calc, start, entries)
+	if len(entries) == 0 {
+		return nil, errors.New("no matches between two teams the calculator can rate to fit")
+	}
+
+	res := &Result{Matches: len(entries), Skipped: skipped}
+
+	eval := func(x []float64) float64 {
+		res.Evals++
+		return logLikelihood(calc, toGameInfo(start, x), entries)
+	}
+
+	x := fromGameInfo(start)
+	best := eval(x)
+	res.InitialLogLikelihood = best
+
+	step := o.InitialStep
+	for step >= o.MinStep && res.Evals < o.MaxEvals {
+		improved := false
+		for i := range x {
+			for _, dir := range []float64{1, -1} {
+				if res.Evals >= o.MaxEvals {
+					break
+				}
+				y := append([]float64{}, x...)
+				y[i] += dir * step
+				if ll := eval(y); ll > best {
+					x, best = y, ll
+					improved = true
+					break
+				}
+			}
+		}
+		res.Sweeps++
+		if !improved {
+			step /= 2
+		}
+	}
+
+	res.GameInfo = toGameInfo(start, x)
+	res.LogLikelihood = best
+	res.Converged = step < o.MinStep
+	return res, nil
+}
+
+// Parameters too close to their bounds are moved inside so that their
+// transforms are finite.
+const (
+	minDeviation = 1e-6
+	minDrawProb  = 1e-6
+)
+
+// Maps the searched parameters of gi to the transformed space.
+func fromGameInfo(gi *skills.GameInfo) []float64 {
+	drawProb := math.Min(math.Max(gi.DrawProbability, minDrawProb), 1-minDrawProb)
+	return []float64{
+		math.Log(math.Max(gi.Beta, minDeviation)),
+		math.Log(math.Max(gi.DynamicsFactor, minDeviation)),
+		math.Log(drawProb / (1 - drawProb)),
+		math.Log(math.Max(gi.InitialStddev, minDeviation)),
+	}
+}
+
+// Maps x back from the transformed space, keeping the other parameters of gi.
+func toGameInfo(gi *skills.GameInfo, x []float64) *skills.GameInfo {
+	fitted := *gi
+	fitted.Beta = math.Exp(x[0])
+	fitted.DynamicsFactor = math.Exp(x[1])
+	fitted.DrawProbability = 1 / (1 + math.Exp(-x[2]))
+	fitted.InitialStddev = math.Exp(x[3])
+	return &fitted
+}
@@ -0,0 +1,100 @@
+package fit
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/matchlog"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Simulates one on one matches between players with fixed true skills whose
+// performances vary with the given beta.
+func simulate(players, matches int, beta, drawMargin float64) []*matchlog.Entry {
+	rnd := rand.New(rand.NewSource(1))
+
+	skill := make([]float64, players)
+	for i := range skill {
+		skill[i] = 25 + 8*rnd.NormFloat64()
+	}
+
+	var es []*matchlog.Entry
+	t := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < matches; i++ {
+		a := rnd.Intn(players)
+		b := (a + 1 + rnd.Intn(players-1)) % players
+		diff := skill[a] + beta*rnd.NormFloat64() - skill[b] - beta*rnd.NormFloat64()
+
+		ranks := []int{1, 2}
+		switch {
+		case math.Abs(diff) < drawMargin:
+			ranks = []int{1, 1}
+		case diff < 0:
+			ranks = []int{2, 1}
+		}
+		es = append(es, &matchlog.Entry{
+			Time:  t.Add(time.Duration(i) * time.Hour),
+			Teams: [][]string{{fmt.Sprint(a)}, {fmt.Sprint(b)}},
+			Ranks: ranks,
+		})
+	}
+	return es
+}
+
+func TestFit(t *testing.T) {
+	const trueBeta = 1.5
+	entries := simulate(30, 1500, trueBeta, 0.3)
+
+	res, err := Fit(&trueskill.TwoPlayerCalc{}, skills.DefaultGameInfo, entries, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.LogLikelihood <= res.InitialLogLikelihood {
+		t.Errorf("LogLikelihood = %v, want more than the initial %v", res.LogLikelihood, res.InitialLogLikelihood)
+	}
+	if ll, n := LogLikelihood(&trueskill.TwoPlayerCalc{}, res.GameInfo, entries); ll != res.LogLikelihood || n != res.Matches {
+		t.Errorf("LogLikelihood(fitted) = %v, %v, want %v, %v", ll, n, res.LogLikelihood, res.Matches)
+	}
+	if b := res.GameInfo.Beta; b >= skills.DefaultGameInfo.Beta {
+		t.Errorf("fitted Beta = %v, want less than the default %v (true beta is %v)", b, skills.DefaultGameInfo.Beta, trueBeta)
+	}
+	if d := res.GameInfo.DrawProbability; d <= 0 || d >= 1 {
+		t.Errorf("fitted DrawProbability = %v, want in (0, 1)", d)
+	}
+	if res.GameInfo.InitialMean != skills.DefaultGameInfo.InitialMean {
+		t.Errorf("fitted InitialMean = %v, want it unchanged", res.GameInfo.InitialMean)
+	}
+	if !res.Converged {
+		t.Errorf("fit did not converge after %v evaluations", res.Evals)
+	}
+}
+
+func TestFitSkipsUnratableMatches(t *testing.T) {
+	entries := simulate(4, 20, 4, 0.5)
+	entries = append(entries, &matchlog.Entry{
+		Teams: [][]string{{"0"}, {"1"}, {"2"}},
+		Ranks: []int{1, 2, 3},
+	}, &matchlog.Entry{
+		Teams: [][]string{{"0", "1"}, {"2", "3"}},
+		Ranks: []int{1, 2},
+	})
+
+	res, err := Fit(&trueskill.TwoPlayerCalc{}, skills.DefaultGameInfo, entries, &Options{MaxEvals: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Matches != 20 || res.Skipped != 2 {
+		t.Errorf("Matches, Skipped = %v, %v, want %v, %v", res.Matches, res.Skipped, 20, 2)
+	}
+	if res.Evals != 10 || res.Converged {
+		t.Errorf("Evals, Converged = %v, %v, want %v, %v", res.Evals, res.Converged, 10, false)
+	}
+
+	if _, err := Fit(&trueskill.TwoPlayerCalc{}, skills.DefaultGameInfo, entries[20:], nil); err == nil {
+		t.Errorf("Fit without two team matches succeeded")
+	}
+}
@@ -0,0 +1,168 @@
+// Package matchlog reads logs of played matches and replays them through a
+// calculator.
+//
+// A log holds one JSON object per line, for example
+//
+//	{"time":"2013-04-01T18:00:00Z","mode":"ranked","teams":[["ann","bob"],["cat","dan"]],"ranks":[1,2]}
+//
+// where each team lists its player ids and ranks follow the same convention
+// as skills.Calc (1 for first place, repeat the number for a tie). Results
+// where only the first places are known give their number as "known" (see
+// skills.Match.Known), and matches that count more or less than usual give
+// their "weight".
+package matchlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"io"
+	"time"
+)
+
+// A single played match.
+type Entry struct {
+	Time  time.Time  `json:"time"`
+	Mode  string     `json:"mode,omitempty"`
+	Teams [][]string `json:"teams"`
+	Ranks []int      `json:"ranks"`
+	Known int        `json:"known,omitempty"`
+
+	// The weight of the match (see skills.Match.Weight); nil means 1.
+	Weight *float64 `json:"weight,omitempty"`
+}
+
+// Returns the number of players on each team of the match, e.g. "2v2".
+func (e *Entry) TeamSize() string {
+	s := ""
+	for i, t := range e.Teams {
+		if i > 0 {
+			s += "v"
+		}
+		s += fmt.Sprint(len(t))
+	}
+	return s
+}
+
+// Returns the players of the match.
+func (e *Entry) Players() []skills.Player {
+	var ps []skills.Player
+	for _, ids := range e.Teams {
+		for _, id := range ids {
+			ps = append(ps, *skills.NewPlayer(id))
+		}
+	}
+	return ps
+}
+
+// Builds the teams of the match using the players' ratings in the store.
+// Players without a rating, or all players if the store is nil, get the
+// game's default rating.
+func (e *Entry) SkillTeams(gi *skills.GameInfo, store skills.RatingStore) []skills.Team {
+	teams := make([]skills.Team, len(e.Teams))
+	for i, ids := range e.Teams {
+		teams[i] = skills.NewTeam()
+		for _, id := range ids {
+			p := *skills.NewPlayer(id)
+			r, ok := skills.Rating{}, false
+			if store != nil {
+				r, ok = store.Rating(p)
+			}
+			if !ok {
+				r = gi.DefaultRating()
+			}
+			teams[i].AddPlayer(p, r)
+		}
+	}
+	return teams
+}
+
+func (e *Entry) validate() error {
+	if len(e.Teams) != len(e.Ranks) {
+		return fmt.Errorf("number of teams [%v] does not match number of ranks [%v]", len(e.Teams), len(e.Ranks))
+	}
+	if e.Known < 0 || e.Known > len(e.Teams) {
+		return fmt.Errorf("known places [%v] outside of [0, %v]", e.Known, len(e.Teams))
+	}
+	if e.Known > 0 {
+		last := e.Ranks[0]
+		for _, r := range e.Ranks[:e.Known] {
+			if r > last {
+				last = r
+			}
+		}
+		for _, r := range e.Ranks[e.Known:] {
+			if r <= last {
+				return fmt.Errorf("rank of unknown place [%v] not below last known place [%v]", r, last)
+			}
+		}
+	}
+	for _, t := range e.Teams {
+		if len(t) == 0 {
+			return fmt.Errorf("empty team")
+		}
+	}
+	return nil
+}
+
+// A Reader reads entries from a match log.
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{s: bufio.NewScanner(r)}
+}
+
+// Read returns the next entry of the log, or io.EOF at the end of the log.
+// Blank lines are skipped.
+func (r *Reader) Read() (*Entry, error) {
+	for r.s.Scan() {
+		r.line++
+		if len(r.s.Bytes()) == 0 {
+			continue
+		}
+		e := &Entry{}
+		if err := json.Unmarshal(r.s.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("line %v: %v", r.line, err)
+		}
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("line %v: %v", r.line, err)
+		}
+		return e, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ReadAll reads all remaining entries of the log.
+func (r *Reader) ReadAll() ([]*Entry, error) {
+	var es []*Entry
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return es, nil
+		}
+		if err != nil {
+			return es, err
+		}
+		es = append(es, e)
+	}
+}
+
+// A Writer writes entries to a match log.
+type Writer struct {
+	enc *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{json.NewEncoder(w)}
+}
+
+func (w *Writer) Write(e *Entry) error {
+	return w.enc.Encode(e)
+}
@@ -0,0 +1,190 @@
+package matchlog
+
+import (
+	"bytes"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testLog = `{"time":"2013-04-01T18:00:00Z","mode":"ranked","teams":[["ann","bob"],["cat","dan"]],"ranks":[1,2]}
+
+{"time":"2013-04-01T19:00:00Z","teams":[["ann"],["cat"]],"ranks":[1,1]}
+`
+
+func TestReadWrite(t *testing.T) {
+	es, err := NewReader(strings.NewReader(testLog)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 2 {
+		t.Fatalf("len(entries) = %v, want %v", len(es), 2)
+	}
+	if e := es[0]; e.Mode != "ranked" || e.TeamSize() != "2v2" || e.Teams[1][0] != "cat" || e.Ranks[1] != 2 {
+		t.Errorf("entries[0] = %+v", e)
+	}
+	if e := es[1]; e.Mode != "" || e.TeamSize() != "1v1" || e.Time.Hour() != 19 {
+		t.Errorf("entries[1] = %+v", e)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, e := range es {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := buf.String(), strings.Replace(testLog, "\n\n", "\n", 1); got != want {
+		t.Errorf("written log = %q, want %q", got, want)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, l := range []string{
+		`{"teams":[["ann"],["bob"]],"ranks":[1]}`,
+		`{"teams":[["ann"],[]],"ranks":[1,2]}`,
+		`{"teams":`,
+		`{"teams":[["ann"],["bob"]],"ranks":[1,2],"known":3}`,
+		`{"teams":[["ann"],["bob"],["cat"]],"ranks":[1,1,2],"known":1}`,
+	} {
+		if _, err := NewReader(strings.NewReader(l)).Read(); err == nil {
+			t.Errorf("Read(%q) succeeded", l)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	es, err := NewReader(strings.NewReader(testLog)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gi := skills.DefaultGameInfo
+	var seen []float64
+	ratings := Replay(&trueskill.TwoTeamCalc{}, gi, es, nil, func(e *Entry, teams []skills.Team) {
+		seen = append(seen, teams[0].PlayerRating(*skills.NewPlayer("ann")).Mean())
+	})
+
+	if len(ratings) != 4 {
+		t.Errorf("len(ratings) = %v, want %v", len(ratings), 4)
+	}
+	if seen[0] != gi.InitialMean || seen[1] <= gi.InitialMean {
+		t.Errorf("ann's prior means = %v, want %v then more", seen, gi.InitialMean)
+	}
+	if r := ratings[*skills.NewPlayer("ann")]; r.Mean() >= seen[1] {
+		t.Errorf("ann's rating after a draw with a weaker player = %v, want a mean below %v", r, seen[1])
+	}
+}
+
+func TestReplaySource(t *testing.T) {
+	es, err := NewReader(strings.NewReader(testLog)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi := skills.DefaultGameInfo
+	calc := &trueskill.TwoTeamCalc{}
+	want := Replay(calc, gi, es, nil, nil)
+
+	got, err := ReplaySource(calc, gi, NewReader(strings.NewReader(testLog)), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, r := range want {
+		if !got[p].Equal(r) {
+			t.Errorf("streamed rating of %v = %v, want %v", p, got[p], r)
+		}
+	}
+
+	// Read errors stop the replay
+	if _, err := ReplaySource(calc, gi, NewReader(strings.NewReader(testLog+"{")), nil, nil); err == nil {
+		t.Errorf("ReplaySource of a broken log succeeded")
+	}
+}
+
+func TestRateKnown(t *testing.T) {
+	e, err := NewReader(strings.NewReader(`{"teams":[["ann"],["bob"],["cat"]],"ranks":[1,2,2],"known":1}`)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Known != 1 {
+		t.Errorf("Known = %v, want %v", e.Known, 1)
+	}
+
+	gi := skills.DefaultGameInfo
+	newRatings := Rate(&trueskill.FFACalc{}, gi, e, e.SkillTeams(gi, nil))
+	ann, bob, cat := newRatings[*skills.NewPlayer("ann")], newRatings[*skills.NewPlayer("bob")], newRatings[*skills.NewPlayer("cat")]
+	if ann.Mean() <= gi.InitialMean || math.Abs(bob.Mean()-cat.Mean()) > 1e-6 || math.Abs(bob.Stddev()-cat.Stddev()) > 1e-6 {
+		t.Errorf("new ratings = %v", newRatings)
+	}
+
+	// bob and cat did not draw, so they learn less than if they had
+	e.Known = 0
+	drawn := Rate(&trueskill.FFACalc{}, gi, e, e.SkillTeams(gi, nil))[*skills.NewPlayer("bob")]
+	if bob.Stddev() <= drawn.Stddev() {
+		t.Errorf("stddev of an unknown place = %v, want more than %v for a draw", bob.Stddev(), drawn.Stddev())
+	}
+}
+
+func TestRateWeight(t *testing.T) {
+	e, err := NewReader(strings.NewReader(`{"teams":[["ann"],["bob"]],"ranks":[1,2],"weight":0}`)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi := skills.DefaultGameInfo
+	for p, r := range Rate(&trueskill.TwoTeamCalc{}, gi, e, e.SkillTeams(gi, nil)) {
+		if r.Mean() != gi.InitialMean || r.Stddev() != gi.InitialStddev {
+			t.Errorf("rating of %v after a match of weight 0 = %v, want the default", p, r)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	es, err := NewReader(strings.NewReader(testLog)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi := skills.DefaultGameInfo
+	if err := Check(&trueskill.TwoPlayerCalc{}, gi, es[0]); err == nil {
+		t.Errorf("Check(TwoPlayerCalc, 2v2) succeeded")
+	}
+	if err := Check(&trueskill.TwoPlayerCalc{}, gi, es[1]); err != nil {
+		t.Errorf("Check(TwoPlayerCalc, 1v1) = %v", err)
+	}
+	if two, skipped := TwoTeamEntries(&trueskill.TwoPlayerCalc{}, gi, es); len(two) != 1 || two[0] != es[1] || skipped != 1 {
+		t.Errorf("TwoTeamEntries = %v, %v", two, skipped)
+	}
+}
+
+// Hides the skills.MatchCalc methods of the calculator it wraps.
+type plainCalc struct {
+	skills.Calc
+}
+
+func TestUnknownPlacesNeedMatchCalc(t *testing.T) {
+	e, err := NewReader(strings.NewReader(`{"teams":[["ann"],["bob"],["cat"]],"ranks":[1,2,2],"known":1}`)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi := skills.DefaultGameInfo
+	calc := plainCalc{&trueskill.FFACalc{}}
+	if err := Check(calc, gi, e); err == nil {
+		t.Errorf("Check of unknown places without a MatchCalc succeeded")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Rate of unknown places without a MatchCalc did not panic")
+			}
+		}()
+		Rate(calc, gi, e, e.SkillTeams(gi, nil))
+	}()
+
+	// Knowing the first two places of three gives the last as well
+	e.Known = 2
+	e.Ranks = []int{1, 2, 3}
+	if err := Check(calc, gi, e); err != nil {
+		t.Errorf("Check with only the last place unknown = %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package matchlog
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"io"
+	"math"
+)
+
+// A calculator that can both rate matches and predict their outcome.
+type Predictor interface {
+	skills.Calc
+	skills.OutcomePredictor
+}
+
+// Predicted probabilities are floored at this so a single impossible outcome
+// can not make a log-likelihood infinite.
+const MinProb = 1e-12
+
+// Called for each entry before its match is rated, with the teams holding the
+// players' ratings going into the match (grown for inactivity, if the game
+// has an inactivity rate).
+type Visitor func(e *Entry, teams []skills.Team)
+
+// A Source yields entries in order and io.EOF after the last one. *Reader is
+// a Source.
+type Source interface {
+	Read() (*Entry, error)
+}
+
+// Replay rates the entries in order, updating ratings in place, and returns
+// the ratings after the last match. If ratings is nil every player starts
+// with the default rating. Entries the calculator cannot rate (see Check)
+// must be filtered out by the caller. If visit is not nil it is called before each
+// match is rated, which lets callers make predictions without peeking at the
+// result.
+func Replay(calc skills.Calc, gi *skills.GameInfo, entries []*Entry, ratings skills.PlayerRatings, visit Visitor) skills.PlayerRatings {
+	if ratings == nil {
+		ratings = make(skills.PlayerRatings)
+	}
+	for _, e := range entries {
+		replayEntry(calc, gi, e, ratings, visit)
+	}
+	return ratings
+}
+
+// ReplaySource is Replay for the entries of a source, read one at a time. It
+// stops at the first error of the source other than io.EOF and returns it
+// along with the ratings so far.
+func ReplaySource(calc skills.Calc, gi *skills.GameInfo, src Source, ratings skills.PlayerRatings, visit Visitor) (skills.PlayerRatings, error) {
+	if ratings == nil {
+		ratings = make(skills.PlayerRatings)
+	}
+	for {
+		e, err := src.Read()
+		if err == io.EOF {
+			return ratings, nil
+		}
+		if err != nil {
+			return ratings, err
+		}
+		replayEntry(calc, gi, e, ratings, visit)
+	}
+}
+
+func replayEntry(calc skills.Calc, gi *skills.GameInfo, e *Entry, ratings skills.PlayerRatings, visit Visitor) {
+	teams := e.SkillTeams(gi, ratings)
+	if visit != nil {
+		visit(e, gi.InactivePriors(teams, e.Time))
+	}
+	for p, r := range Rate(calc, gi, e, teams) {
+		ratings[p] = r
+	}
+}
+
+// Rate rates the match of an entry between the given teams. Calculators
+// implementing skills.MatchCalc are given the time, known places and weight
+// of the entry; for others the priors are grown for inactivity here, the new
+// ratings are stamped with the time and the weight is ignored. It panics if
+// the entry has unknown places and the calculator can not take them.
+func Rate(calc skills.Calc, gi *skills.GameInfo, e *Entry, teams []skills.Team) skills.PlayerRatings {
+	if err := checkKnown(calc, e); err != nil {
+		panic(err)
+	}
+	if mc, ok := calc.(skills.MatchCalc); ok {
+		m := skills.NewPartialMatch(teams, e.Known, e.Ranks...)
+		m.Time = e.Time
+		m.Weight = e.Weight
+		return mc.CalcMatch(gi, m)
+	}
+
+	newRatings := calc.CalcNewRatings(gi, gi.InactivePriors(teams, e.Time), e.Ranks...)
+	if !e.Time.IsZero() {
+		for p, r := range newRatings {
+			newRatings[p] = r.PlayedAt(e.Time)
+		}
+	}
+	return newRatings
+}
+
+// Check returns an error if the calculator can not rate the entry: its teams
+// are outside the calculator's range (as found by its CalcMatchQual), or it
+// has unknown places and the calculator does not implement skills.MatchCalc.
+func Check(calc skills.Calc, gi *skills.GameInfo, e *Entry) (err error) {
+	if err := checkKnown(calc, e); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	calc.CalcMatchQual(gi, e.SkillTeams(gi, nil))
+	return nil
+}
+
+// Returns the entries of matches between two teams that the calculator can
+// rate, and the number of other entries.
+func TwoTeamEntries(calc skills.Calc, gi *skills.GameInfo, entries []*Entry) (two []*Entry, skipped int) {
+	for _, e := range entries {
+		if len(e.Teams) == 2 && Check(calc, gi, e) == nil {
+			two = append(two, e)
+		} else {
+			skipped++
+		}
+	}
+	return
+}
+
+// Returns the result of a match between two teams for the first team:
+// skills.Win, skills.Draw or skills.Lose.
+func (e *Entry) Result() int {
+	switch {
+	case e.Ranks[0] < e.Ranks[1]:
+		return skills.Win
+	case e.Ranks[0] > e.Ranks[1]:
+		return skills.Lose
+	}
+	return skills.Draw
+}
+
+// Returns the predicted probability of the result of a match between two
+// teams, floored at MinProb.
+func (e *Entry) ResultProb(win, draw, lose float64) float64 {
+	p := draw
+	switch e.Result() {
+	case skills.Win:
+		p = win
+	case skills.Lose:
+		p = lose
+	}
+	return math.Max(p, MinProb)
+}
+
+// Only a skills.MatchCalc can rate teams in unknown places; a single unknown
+// place is the last one, which the ranks already give.
+func checkKnown(calc skills.Calc, e *Entry) error {
+	if _, ok := calc.(skills.MatchCalc); !ok && e.Known > 0 && e.Known < len(e.Teams)-1 {
+		return fmt.Errorf("%v known places of %v teams need a skills.MatchCalc", e.Known, len(e.Teams))
+	}
+	return nil
+}
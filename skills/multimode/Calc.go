@@ -0,0 +1,70 @@
+package multimode
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+)
+
+// Calc rates matches in one mode of a model. The ratings on the teams are
+// ignored; players are rated with their marginals for the mode and
+// CalcNewRatings updates their joints in the model.
+type Calc struct {
+	Calc  skills.Calc
+	Model *Model
+	Mode  string
+}
+
+// Calculates new ratings for the players in the mode and updates the model.
+func (c *Calc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return c.CalcMatch(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Calculates new ratings for the players of the match in the mode and
+// updates the model, with the priors the underlying calculator updated them
+// from if it is a skills.UpdateCalc (see Model.Update). The match is passed
+// on to the underlying calculator if it is a skills.MatchCalc.
+func (c *Calc) CalcMatch(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	mm := *m
+	mm.Teams = c.Priors(gi, m.Teams)
+	var newRatings, priors skills.PlayerRatings
+	switch calc := c.Calc.(type) {
+	case skills.UpdateCalc:
+		newRatings, priors = calc.CalcUpdate(gi, &mm)
+	case skills.MatchCalc:
+		newRatings = calc.CalcMatch(gi, &mm)
+	default:
+		newRatings = calc.CalcNewRatings(gi, mm.Teams, mm.Ranks...)
+	}
+	c.Model.Update(gi, c.Mode, mm.Teams, priors, newRatings)
+	return newRatings
+}
+
+// Calculates new ratings for the players in the mode like CalcNewRatings,
+// without updating the model.
+func (c *Calc) Preview(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return c.Calc.CalcNewRatings(gi, c.Priors(gi, teams), ranks...)
+}
+
+// Returns copies of the teams with each player's rating in the mode, the
+// ratings a match between them is rated from.
+func (c *Calc) Priors(gi *skills.GameInfo, teams []skills.Team) []skills.Team {
+	return c.Model.Teams(c.Mode, teams)
+}
+
+// Returns the underlying calculator.
+func (c *Calc) Unwrap() skills.Calc {
+	return c.Calc
+}
+
+// Calculates the match quality from the players' ratings in the mode.
+func (c *Calc) CalcMatchQual(gi *skills.GameInfo, teams []skills.Team) float64 {
+	return c.Calc.CalcMatchQual(gi, c.Priors(gi, teams))
+}
+
+// Calculates the match quality from the same priors as CalcMatchQual with
+// the underlying calculator's skills.Match.Quality, which also takes the rest
+// of the match into account.
+func (c *Calc) MatchQual(gi *skills.GameInfo, m *skills.Match) float64 {
+	qm := *m
+	qm.Teams = c.Priors(gi, m.Teams)
+	return qm.Quality(c.Calc, gi)
+}
@@ -0,0 +1,152 @@
+// Package multimode rates players in several game modes whose skills are
+// correlated.
+//
+// Each player has a joint Gaussian over their skills in every mode, kept in
+// precision form (precision matrix Λ and precision-mean vector η). A match in
+// one mode is rated with the player's marginal for that mode; the change the
+// calculator makes to that marginal is then added to the joint as a message
+// on the mode's coordinate, so through the correlations a result in one mode
+// partially informs the others.
+package multimode
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+)
+
+// A Model holds the joint skill distribution of every player.
+type Model struct {
+	modes []string
+
+	// The joint of players who have not played yet
+	prior joint
+
+	players map[skills.Player]joint
+}
+
+// A joint Gaussian in precision form.
+type joint struct {
+	precision     *numerics.Matrix
+	precisionMean *numerics.Matrix
+}
+
+// Creates a model of the modes where new players have the game's initial
+// mean and standard deviation in every mode and the skills in modes i and
+// j have the given correlation.
+func NewModel(gi *skills.GameInfo, modes []string, correlation *numerics.Matrix) *Model {
+	n := len(modes)
+	if correlation.Rows() != n || correlation.Cols() != n {
+		panic(fmt.Errorf("%vx%v correlation matrix for %v modes", correlation.Rows(), correlation.Cols(), n))
+	}
+
+	cov := new(numerics.Matrix).Scale(numerics.Sqr(gi.InitialStddev), correlation)
+	mean := numerics.NewMatrix(n, 1)
+	for i := 0; i < n; i++ {
+		mean.SetAt(i, 0, gi.InitialMean)
+	}
+
+	precision := new(numerics.Matrix).Inverse(cov)
+	return &Model{
+		modes:   append([]string{}, modes...),
+		prior:   joint{precision, new(numerics.Matrix).Mul(precision, mean)},
+		players: make(map[skills.Player]joint),
+	}
+}
+
+// Creates a model where the skills in every pair of modes have the same correlation.
+func NewUniformModel(gi *skills.GameInfo, modes []string, correlation float64) *Model {
+	n := len(modes)
+	r := numerics.NewIdentityMatrix(n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j {
+				r.SetAt(i, j, correlation)
+			}
+		}
+	}
+	return NewModel(gi, modes, r)
+}
+
+func (m *Model) mode(name string) int {
+	for i, mode := range m.modes {
+		if mode == name {
+			return i
+		}
+	}
+	panic(fmt.Errorf("unknown mode %q", name))
+}
+
+func (m *Model) joint(p skills.Player) joint {
+	if j, ok := m.players[p]; ok {
+		return j
+	}
+	return m.prior
+}
+
+// Returns the mean vector and covariance matrix of a player's skills.
+func (m *Model) Joint(p skills.Player) (mean, cov *numerics.Matrix) {
+	j := m.joint(p)
+	cov = new(numerics.Matrix).Inverse(j.precision)
+	mean = new(numerics.Matrix).Mul(cov, j.precisionMean)
+	return
+}
+
+// Returns a player's rating in a mode, the marginal of their joint.
+func (m *Model) Rating(p skills.Player, mode string) skills.Rating {
+	k := m.mode(mode)
+	mean, cov := m.Joint(p)
+	return skills.NewRating(mean.At(k, 0), math.Sqrt(cov.At(k, k)))
+}
+
+// Returns copies of the teams with each player's rating replaced by their
+// rating in the mode.
+func (m *Model) Teams(mode string, teams []skills.Team) []skills.Team {
+	mteams := make([]skills.Team, len(teams))
+	for i, t := range teams {
+		mteams[i] = t.MapRatings(func(p skills.Player, r skills.Rating) skills.Rating {
+			return m.Rating(p, mode)
+		})
+	}
+	return mteams
+}
+
+// Update incorporates the result of a match in a mode. teams are the teams
+// the match was rated with (see Teams), newRatings the calculator's result
+// and priors the ratings it updated them from (see skills.UpdateCalc). A
+// player missing from priors is taken to have been updated from their rating
+// in the mode with DynamicsFactor² added. Anchored players are left as they
+// are.
+//
+// The variance the calculator added to the player's rating in the mode is
+// first added to the joint on the mode's coordinate. The message from the
+// match is then the posterior divided by the prior, which is added to the
+// joint's precision and precision-mean.
+func (m *Model) Update(gi *skills.GameInfo, mode string, teams []skills.Team, priors, newRatings skills.PlayerRatings) {
+	k := m.mode(mode)
+
+	for _, t := range teams {
+		for _, p := range t.Players() {
+			post, ok := newRatings[p]
+			if !ok || t.Attrs(p).Anchor != skills.NotAnchored {
+				continue
+			}
+
+			mean, cov := m.Joint(p)
+			prior, ok := priors[p]
+			if !ok {
+				prior = skills.NewRating(mean.At(k, 0), math.Sqrt(cov.At(k, k)+numerics.Sqr(gi.DynamicsFactor)))
+			}
+			cov.SetAt(k, k, math.Max(prior.Variance(), cov.At(k, k)))
+
+			precision := new(numerics.Matrix).Inverse(cov)
+			precisionMean := new(numerics.Matrix).Mul(precision, mean)
+
+			precision.SetAt(k, k, precision.At(k, k)+1/post.Variance()-1/prior.Variance())
+			precisionMean.SetAt(k, 0, precisionMean.At(k, 0)+post.Mean()/post.Variance()-prior.Mean()/prior.Variance())
+
+			m.players[p] = joint{precision, precisionMean}
+		}
+	}
+}
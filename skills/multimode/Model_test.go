@@ -0,0 +1,118 @@
+package multimode
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"testing"
+)
+
+var modes = []string{"ranked", "casual", "tournament"}
+
+// Plays a ranked match that ann wins against bob and returns the model.
+func playRanked(rho float64) (*Model, skills.PlayerRatings) {
+	gi := skills.DefaultGameInfo
+	m := NewUniformModel(gi, modes, rho)
+
+	team1 := skills.NewTeam()
+	team1.AddPlayer(*skills.NewPlayer("ann"), gi.DefaultRating())
+	team2 := skills.NewTeam()
+	team2.AddPlayer(*skills.NewPlayer("bob"), gi.DefaultRating())
+
+	calc := &Calc{&trueskill.TwoPlayerCalc{}, m, "ranked"}
+	return m, calc.CalcNewRatings(gi, []skills.Team{team1, team2}, 1, 2)
+}
+
+func closeRatings(a, b skills.Rating) bool {
+	return math.Abs(a.Mean()-b.Mean()) < 1e-9 && math.Abs(a.Stddev()-b.Stddev()) < 1e-9
+}
+
+func TestUncorrelatedModes(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann := *skills.NewPlayer("ann")
+	m, newRatings := playRanked(0)
+
+	if r := m.Rating(ann, "ranked"); !closeRatings(r, newRatings[ann]) {
+		t.Errorf("ranked rating = %v, want the calculator's %v", r, newRatings[ann])
+	}
+	for _, mode := range modes[1:] {
+		if r := m.Rating(ann, mode); !closeRatings(r, gi.DefaultRating()) {
+			t.Errorf("%v rating = %v, want the default %v", mode, r, gi.DefaultRating())
+		}
+	}
+}
+
+func TestCorrelatedModes(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann := *skills.NewPlayer("ann")
+	bob := *skills.NewPlayer("bob")
+	m, newRatings := playRanked(0.6)
+
+	if r := m.Rating(ann, "ranked"); !closeRatings(r, newRatings[ann]) {
+		t.Errorf("ranked rating = %v, want the calculator's %v", r, newRatings[ann])
+	}
+
+	// The win partially carries over to the other modes
+	ranked := m.Rating(ann, "ranked")
+	for _, mode := range modes[1:] {
+		r := m.Rating(ann, mode)
+		if !(r.Mean() > gi.InitialMean && r.Mean() < ranked.Mean()) {
+			t.Errorf("ann's %v mean = %v, want between %v and %v", mode, r.Mean(), gi.InitialMean, ranked.Mean())
+		}
+		if !(r.Stddev() < gi.InitialStddev && r.Stddev() > ranked.Stddev()) {
+			t.Errorf("ann's %v stddev = %v, want between %v and %v", mode, r.Stddev(), ranked.Stddev(), gi.InitialStddev)
+		}
+		if r := m.Rating(bob, mode); r.Mean() >= gi.InitialMean {
+			t.Errorf("bob's %v mean = %v, want less than %v", mode, r.Mean(), gi.InitialMean)
+		}
+	}
+
+	// The covariance stays symmetric
+	_, cov := m.Joint(ann)
+	for i := range modes {
+		for j := range modes {
+			if math.Abs(cov.At(i, j)-cov.At(j, i)) > 1e-9 {
+				t.Errorf("cov[%v][%v] = %v, cov[%v][%v] = %v", i, j, cov.At(i, j), j, i, cov.At(j, i))
+			}
+		}
+	}
+}
+
+func TestUpdateFromCalcPriors(t *testing.T) {
+	gi := *skills.DefaultGameInfo
+	gi.Leavers = &skills.LeaverPolicy{Opponents: 0.5}
+	ann, bob, cat := *skills.NewPlayer("ann"), *skills.NewPlayer("bob"), *skills.NewPlayer("cat")
+
+	for _, calc := range []skills.Calc{&trueskill.TwoTeamCalc{}, &trueskill.FFACalc{}} {
+		m := NewUniformModel(&gi, modes, 0.6)
+		mc := &Calc{calc, m, "ranked"}
+
+		// A match of half weight, where bob leaves and cat is a bot of fixed
+		// skill: the model takes on the ratings the calculator returns
+		team1 := skills.NewTeam()
+		team1.AddPlayer(ann, gi.DefaultRating())
+		team2 := skills.NewTeam()
+		team2.AddPlayerAttrs(bob, gi.DefaultRating(), skills.PlayerAttrs{Weight: 1, Status: skills.AFK})
+		team3 := skills.NewTeam()
+		team3.AddPlayerAttrs(cat, gi.DefaultRating(), skills.PlayerAttrs{Weight: 1, Anchor: skills.Anchored})
+		teams := []skills.Team{team1, team2, team3}
+		ranks := []int{1, 2, 3}
+		if _, ok := calc.(*trueskill.TwoTeamCalc); ok {
+			team2.AddPlayerAttrs(cat, gi.DefaultRating(), skills.PlayerAttrs{Weight: 1, Anchor: skills.Anchored})
+			teams, ranks = teams[:2], ranks[:2]
+		}
+		match := skills.NewMatch(teams, ranks...)
+		half := 0.5
+		match.Weight = &half
+
+		newRatings := mc.CalcMatch(&gi, match)
+		for _, p := range []skills.Player{ann, bob} {
+			if r := m.Rating(p, "ranked"); !closeRatings(r, newRatings[p]) {
+				t.Errorf("%T: %v's rating = %v, want the calculator's %v", calc, p, r, newRatings[p])
+			}
+		}
+		if r := m.Rating(cat, "ranked"); !closeRatings(r, gi.DefaultRating()) {
+			t.Errorf("%T: anchored cat's rating = %v, want it unchanged", calc, r)
+		}
+	}
+}
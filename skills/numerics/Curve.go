@@ -0,0 +1,53 @@
+package numerics
+
+import (
+	"math"
+)
+
+// A Curve is the distribution of a performance difference in units of its
+// standard deviation. It decides how likely each outcome is for a given
+// difference in skill, the way Elo variants differ only by their curve.
+// Curves are symmetric about 0.
+type Curve interface {
+	Cdf(x float64) float64
+	Pdf(x float64) float64
+
+	// The derivative of the Pdf.
+	PdfDeriv(x float64) float64
+
+	// The inverse of the Cdf.
+	InvCdf(p float64) float64
+}
+
+// The standard normal distribution, as used by TrueSkill. Its Cdf keeps its
+// relative accuracy far into the lower tail, where GaussCumulativeTo does not.
+type GaussCurve struct{}
+
+func (GaussCurve) Cdf(x float64) float64      { return math.Erfc(-x/math.Sqrt2) / 2 }
+func (GaussCurve) Pdf(x float64) float64      { return GaussAt(x) }
+func (GaussCurve) PdfDeriv(x float64) float64 { return -x * GaussAt(x) }
+func (GaussCurve) InvCdf(p float64) float64   { return GaussInvCumulativeTo(p, 0, 1) }
+
+// The logistic distribution with unit variance, as used by Elo (and FIDE).
+// Its heavier tails make upsets less surprising than under the Gaussian.
+type LogisticCurve struct{}
+
+// The scale of a logistic distribution with unit variance
+const logisticScale = 0.55132889542179204 // sqrt(3) / pi
+
+func (LogisticCurve) Cdf(x float64) float64 {
+	return 1 / (1 + math.Exp(-x/logisticScale))
+}
+
+func (LogisticCurve) Pdf(x float64) float64 {
+	e := math.Exp(-math.Abs(x) / logisticScale)
+	return e / (logisticScale * Sqr(1+e))
+}
+
+func (c LogisticCurve) PdfDeriv(x float64) float64 {
+	return c.Pdf(x) * (1 - 2*c.Cdf(x)) / logisticScale
+}
+
+func (LogisticCurve) InvCdf(p float64) float64 {
+	return logisticScale * math.Log(p/(1-p))
+}
@@ -0,0 +1,34 @@
+package numerics
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestCurves(t *testing.T) {
+	for _, c := range []Curve{GaussCurve{}, LogisticCurve{}} {
+		Convey(fmt.Sprintf("Given the curve %T", c), t, func() {
+			Convey("The Pdf is the derivative of the Cdf", func() {
+				const h = 1e-5
+				for x := -5.0; x <= 5; x += 0.25 {
+					So(c.Pdf(x), ShouldAlmostEqual, (c.Cdf(x+h)-c.Cdf(x-h))/(2*h), 1e-8)
+					So(c.PdfDeriv(x), ShouldAlmostEqual, (c.Pdf(x+h)-c.Pdf(x-h))/(2*h), 1e-8)
+				}
+			})
+			Convey("InvCdf inverts the Cdf", func() {
+				for _, p := range []float64{0.01, 0.2, 0.5, 0.75, 0.99} {
+					So(c.Cdf(c.InvCdf(p)), ShouldAlmostEqual, p, 1e-6)
+				}
+			})
+			Convey("It has unit variance", func() {
+				var v float64
+				const dx = 1e-3
+				for x := -40.0; x <= 40; x += dx {
+					v += x * x * c.Pdf(x) * dx
+				}
+				So(v, ShouldAlmostEqual, 1, 1e-6)
+			})
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package numerics
+
+import (
+	"fmt"
+	"math"
+)
+
+// A dense matrix of float64 values stored in row-major order.
+type Matrix struct {
+	rows, cols int
+	values     []float64
+}
+
+// Constructs a rows by cols matrix from values given row by row. With no
+// values the matrix is all zeros.
+func NewMatrix(rows, cols int, values ...float64) *Matrix {
+	if len(values) == 0 {
+		values = make([]float64, rows*cols)
+	} else if len(values) != rows*cols {
+		panic(fmt.Errorf("%v values for a %vx%v matrix", len(values), rows, cols))
+	}
+	return &Matrix{rows, cols, append([]float64{}, values...)}
+}
+
+// Constructs a square matrix with the given values on its diagonal.
+func NewDiagonalMatrix(diag ...float64) *Matrix {
+	m := NewMatrix(len(diag), len(diag))
+	for i, v := range diag {
+		m.SetAt(i, i, v)
+	}
+	return m
+}
+
+// Constructs an n by n identity matrix.
+func NewIdentityMatrix(n int) *Matrix {
+	m := NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		m.SetAt(i, i, 1)
+	}
+	return m
+}
+
+// Constructs a column vector.
+func NewVector(values ...float64) *Matrix {
+	return NewMatrix(len(values), 1, values...)
+}
+
+func (m *Matrix) Rows() int { return m.rows }
+func (m *Matrix) Cols() int { return m.cols }
+
+func (m *Matrix) At(i, j int) float64 {
+	return m.values[i*m.cols+j]
+}
+
+func (m *Matrix) SetAt(i, j int, v float64) {
+	m.values[i*m.cols+j] = v
+}
+
+func (m *Matrix) String() string {
+	s := "["
+	for i := 0; i < m.rows; i++ {
+		if i > 0 {
+			s += "; "
+		}
+		for j := 0; j < m.cols; j++ {
+			if j > 0 {
+				s += " "
+			}
+			s += fmt.Sprintf("%.6g", m.At(i, j))
+		}
+	}
+	return s + "]"
+}
+
+// Set sets z to a copy of x and returns z.
+func (z *Matrix) Set(x *Matrix) *Matrix {
+	z.rows, z.cols = x.rows, x.cols
+	z.values = append(z.values[:0], x.values...)
+	return z
+}
+
+// Add sets z to the sum x+y and returns z.
+func (z *Matrix) Add(x, y *Matrix) *Matrix {
+	if x.rows != y.rows || x.cols != y.cols {
+		panic(fmt.Errorf("adding %vx%v and %vx%v matrices", x.rows, x.cols, y.rows, y.cols))
+	}
+	values := make([]float64, len(x.values))
+	for i := range values {
+		values[i] = x.values[i] + y.values[i]
+	}
+	z.rows, z.cols, z.values = x.rows, x.cols, values
+	return z
+}
+
+// Scale sets z to the product a*x and returns z.
+func (z *Matrix) Scale(a float64, x *Matrix) *Matrix {
+	values := make([]float64, len(x.values))
+	for i, v := range x.values {
+		values[i] = a * v
+	}
+	z.rows, z.cols, z.values = x.rows, x.cols, values
+	return z
+}
+
+// Mul sets z to the product x*y and returns z.
+func (z *Matrix) Mul(x, y *Matrix) *Matrix {
+	if x.cols != y.rows {
+		panic(fmt.Errorf("multiplying %vx%v and %vx%v matrices", x.rows, x.cols, y.rows, y.cols))
+	}
+	values := make([]float64, x.rows*y.cols)
+	for i := 0; i < x.rows; i++ {
+		for j := 0; j < y.cols; j++ {
+			sum := 0.0
+			for k := 0; k < x.cols; k++ {
+				sum += x.At(i, k) * y.At(k, j)
+			}
+			values[i*y.cols+j] = sum
+		}
+	}
+	z.rows, z.cols, z.values = x.rows, y.cols, values
+	return z
+}
+
+// Transpose sets z to the transpose of x and returns z.
+func (z *Matrix) Transpose(x *Matrix) *Matrix {
+	values := make([]float64, len(x.values))
+	for i := 0; i < x.rows; i++ {
+		for j := 0; j < x.cols; j++ {
+			values[j*x.rows+i] = x.At(i, j)
+		}
+	}
+	z.rows, z.cols, z.values = x.cols, x.rows, values
+	return z
+}
+
+// Inverse sets z to the inverse of the square matrix x and returns z. It
+// panics if x is singular.
+func (z *Matrix) Inverse(x *Matrix) *Matrix {
+	if x.rows != x.cols {
+		panic(fmt.Errorf("inverting a %vx%v matrix", x.rows, x.cols))
+	}
+
+	// Gauss-Jordan elimination with partial pivoting on [x | I]
+	n := x.rows
+	a := NewMatrix(n, n).Set(x)
+	inv := NewIdentityMatrix(n)
+	for c := 0; c < n; c++ {
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a.At(r, c)) > math.Abs(a.At(p, c)) {
+				p = r
+			}
+		}
+		if a.At(p, c) == 0 {
+			panic(fmt.Errorf("inverting a singular matrix %v", x))
+		}
+		a.swapRows(c, p)
+		inv.swapRows(c, p)
+
+		d := a.At(c, c)
+		for j := 0; j < n; j++ {
+			a.SetAt(c, j, a.At(c, j)/d)
+			inv.SetAt(c, j, inv.At(c, j)/d)
+		}
+		for r := 0; r < n; r++ {
+			if f := a.At(r, c); r != c && f != 0 {
+				for j := 0; j < n; j++ {
+					a.SetAt(r, j, a.At(r, j)-f*a.At(c, j))
+					inv.SetAt(r, j, inv.At(r, j)-f*inv.At(c, j))
+				}
+			}
+		}
+	}
+	return z.Set(inv)
+}
+
+// Returns the determinant of a square matrix.
+func (m *Matrix) Determinant() float64 {
+	if m.rows != m.cols {
+		panic(fmt.Errorf("determinant of a %vx%v matrix", m.rows, m.cols))
+	}
+
+	// Gaussian elimination with partial pivoting; the determinant is the
+	// product of the pivots, negated for each row swap
+	n := m.rows
+	a := NewMatrix(n, n).Set(m)
+	det := 1.0
+	for c := 0; c < n; c++ {
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a.At(r, c)) > math.Abs(a.At(p, c)) {
+				p = r
+			}
+		}
+		if a.At(p, c) == 0 {
+			return 0
+		}
+		if p != c {
+			a.swapRows(c, p)
+			det = -det
+		}
+		det *= a.At(c, c)
+		for r := c + 1; r < n; r++ {
+			f := a.At(r, c) / a.At(c, c)
+			for j := c; j < n; j++ {
+				a.SetAt(r, j, a.At(r, j)-f*a.At(c, j))
+			}
+		}
+	}
+	return det
+}
+
+// Returns whether x and y have the same shape and their values differ by
+// at most tolerance.
+func AlmostEqual(x, y *Matrix, tolerance float64) bool {
+	if x.rows != y.rows || x.cols != y.cols {
+		return false
+	}
+	for i := range x.values {
+		if math.Abs(x.values[i]-y.values[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Matrix) swapRows(i, j int) {
+	for k := 0; k < m.cols; k++ {
+		m.values[i*m.cols+k], m.values[j*m.cols+k] = m.values[j*m.cols+k], m.values[i*m.cols+k]
+	}
+}
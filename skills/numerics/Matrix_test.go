@@ -0,0 +1,90 @@
+package numerics
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestDeterminant(t *testing.T) {
+	// Ported from the C# MatrixTests
+	Convey("Given square matrices", t, func() {
+		Convey("The determinants of 2x2 matrices are correct", func() {
+			So(NewMatrix(2, 2, 1, 2, 3, 4).Determinant(), ShouldAlmostEqual, -2, errorTolerance)
+			So(NewMatrix(2, 2, 3, 4, 5, 6).Determinant(), ShouldAlmostEqual, -2, errorTolerance)
+			So(NewMatrix(2, 2, 1, 1, 1, 1).Determinant(), ShouldAlmostEqual, 0, errorTolerance)
+			So(NewMatrix(2, 2, 12, 15, 17, 21).Determinant(), ShouldAlmostEqual, 12*21-15*17, errorTolerance)
+		})
+
+		Convey("The determinants of 3x3 matrices are correct", func() {
+			So(NewMatrix(3, 3, 1, 2, 3, 4, 5, 6, 7, 8, 9).Determinant(), ShouldAlmostEqual, 0, errorTolerance)
+			So(NewMatrix(3, 3, 3, 1, 4, 1, 5, 9, 2, 6, 5).Determinant(), ShouldAlmostEqual, -90, errorTolerance)
+		})
+
+		Convey("The determinants of 4x4 matrices are correct", func() {
+			So(NewMatrix(4, 4, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16).Determinant(), ShouldAlmostEqual, 0, errorTolerance)
+			So(NewMatrix(4, 4, 3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 8, 9, 7, 9, 3).Determinant(), ShouldAlmostEqual, 98, errorTolerance)
+		})
+
+		Convey("The determinant of an 8x8 matrix is correct", func() {
+			π := NewMatrix(8, 8,
+				3, 1, 4, 1, 5, 9, 2, 6,
+				5, 3, 5, 8, 9, 7, 9, 3,
+				2, 3, 8, 4, 6, 2, 6, 4,
+				3, 3, 8, 3, 2, 7, 9, 5,
+				0, 2, 8, 8, 4, 1, 9, 7,
+				1, 6, 9, 3, 9, 9, 3, 7,
+				5, 1, 0, 5, 8, 2, 0, 9,
+				7, 4, 9, 4, 4, 5, 9, 2)
+			So(π.Determinant(), ShouldAlmostEqual, 1378143, 0.0001)
+		})
+	})
+}
+
+func TestTranspose(t *testing.T) {
+	Convey("Given a 3x2 matrix", t, func() {
+		e := NewMatrix(3, 2, 1, 4, 2, 5, 3, 6)
+
+		Convey("Its transpose is the 2x3 matrix with rows and columns swapped", func() {
+			So(AlmostEqual(new(Matrix).Transpose(e), NewMatrix(2, 3, 1, 2, 3, 4, 5, 6), 0), ShouldBeTrue)
+		})
+	})
+}
+
+func TestMatrixMul(t *testing.T) {
+	Convey("Given two matrices", t, func() {
+		a := NewMatrix(2, 3, 1, 2, 3, 4, 5, 6)
+		b := NewMatrix(3, 2, 7, 8, 9, 10, 11, 12)
+
+		Convey("Their product is correct", func() {
+			So(AlmostEqual(new(Matrix).Mul(a, b), NewMatrix(2, 2, 58, 64, 139, 154), 0), ShouldBeTrue)
+		})
+
+		Convey("The product can be stored in an operand", func() {
+			So(AlmostEqual(a.Mul(a, b), NewMatrix(2, 2, 58, 64, 139, 154), 0), ShouldBeTrue)
+		})
+	})
+}
+
+func TestMatrixInverse(t *testing.T) {
+	Convey("Given an invertible matrix", t, func() {
+		a := NewMatrix(3, 3, 3, 1, 4, 1, 5, 9, 2, 6, 5)
+
+		Convey("Its product with its inverse is the identity", func() {
+			inv := new(Matrix).Inverse(a)
+			So(AlmostEqual(new(Matrix).Mul(a, inv), NewIdentityMatrix(3), errorTolerance), ShouldBeTrue)
+			So(AlmostEqual(new(Matrix).Mul(inv, a), NewIdentityMatrix(3), errorTolerance), ShouldBeTrue)
+		})
+
+		Convey("The inverse of a diagonal matrix is the reciprocal of its diagonal", func() {
+			So(AlmostEqual(new(Matrix).Inverse(NewDiagonalMatrix(2, 4, 5)), NewDiagonalMatrix(0.5, 0.25, 0.2), errorTolerance), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a singular matrix", t, func() {
+		a := NewMatrix(2, 2, 1, 1, 1, 1)
+
+		Convey("Inverting it panics", func() {
+			So(func() { new(Matrix).Inverse(a) }, ShouldPanic)
+		})
+	})
+}
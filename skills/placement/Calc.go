@@ -0,0 +1,221 @@
+// Package placement rates new players through their placement matches.
+//
+// A player in placement is rated with a larger dynamics factor than usual, so
+// their rating moves quickly toward their skill, while the conservative rating
+// shown to them never goes down. Once their rating is certain enough they are
+// placed: they leave placement and are given a starting tier on a ladder.
+// From then on they are shown their conservative rating as it is, which may
+// be below the highest one shown during placement; callers showing ratings
+// rather than tiers can ease the drop with Event.Shown.
+package placement
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/display"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+)
+
+// The placing of a player.
+type Event struct {
+	Player skills.Player
+	Rating skills.Rating
+
+	// The recommended starting tier and division.
+	Placement display.Placement
+
+	// The highest conservative rating shown to the player in placement.
+	Shown float64
+}
+
+// A Store holds the placement state of players: whether they are in
+// placement and the highest conservative rating shown to them so far. Keeping
+// it next to the players' ratings lets placement carry over restarts.
+type Store interface {
+	// Returns the highest conservative rating shown to a player and whether
+	// the player is in placement.
+	Shown(p skills.Player) (float64, bool)
+
+	SetShown(p skills.Player, shown float64)
+
+	// Takes a player out of placement.
+	Delete(p skills.Player)
+}
+
+// A Store in memory, holding the highest conservative rating shown to each
+// player in placement.
+type ShownRatings map[skills.Player]float64
+
+func (sr ShownRatings) Shown(p skills.Player) (float64, bool) {
+	shown, ok := sr[p]
+	return shown, ok
+}
+
+func (sr ShownRatings) SetShown(p skills.Player, shown float64) {
+	sr[p] = shown
+}
+
+func (sr ShownRatings) Delete(p skills.Player) {
+	delete(sr, p)
+}
+
+// Calc rates matches with an underlying calculator, treating the players in
+// placement as described in the package documentation.
+type Calc struct {
+	Calc skills.Calc
+
+	// The dynamics factor of players in placement, used instead of
+	// GameInfo.DynamicsFactor.
+	DynamicsFactor float64
+
+	// Players are placed once their standard deviation drops below this.
+	PlacedStddev float64
+
+	// The ladder starting tiers are recommended on; nil leaves
+	// Event.Placement zero.
+	Ladder *display.Ladder
+
+	// If not nil, called once for every player placed.
+	OnPlaced func(e Event)
+
+	// The players placed, in order, whether or not OnPlaced is set. Callers
+	// handling them here rather than in OnPlaced take them out, e.g. by
+	// setting Placed to nil.
+	Placed []Event
+
+	// The placement state of the players; nil is an empty ShownRatings,
+	// created on first use.
+	Store Store
+}
+
+func NewCalc(calc skills.Calc, dynamicsFactor, placedStddev float64, ladder *display.Ladder) *Calc {
+	return &Calc{
+		Calc:           calc,
+		DynamicsFactor: dynamicsFactor,
+		PlacedStddev:   placedStddev,
+		Ladder:         ladder,
+		Store:          make(ShownRatings),
+	}
+}
+
+func (c *Calc) store() Store {
+	if c.Store == nil {
+		c.Store = make(ShownRatings)
+	}
+	return c.Store
+}
+
+// Flags a player as in placement.
+func (c *Calc) Begin(p skills.Player) {
+	if !c.InPlacement(p) {
+		c.store().SetShown(p, math.Inf(-1))
+	}
+}
+
+// Returns whether a player is in placement.
+func (c *Calc) InPlacement(p skills.Player) bool {
+	_, ok := c.store().Shown(p)
+	return ok
+}
+
+// Returns the conservative rating shown to a player with the given rating:
+// for players in placement the highest one reached so far.
+func (c *Calc) Shown(p skills.Player, r skills.Rating) float64 {
+	if shown, ok := c.store().Shown(p); ok {
+		return math.Max(shown, r.ConservativeRating())
+	}
+	return r.ConservativeRating()
+}
+
+// Calculates new ratings based on the prior ratings and team ranks use 1 for first place, repeat the number for a tie (e.g. 1, 2, 2).
+func (c *Calc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return c.CalcMatch(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Calculates new ratings for the players of the match and places the players
+// in placement whose ratings became certain enough. The match is passed on to
+// the underlying calculator if it is a skills.MatchCalc.
+func (c *Calc) CalcMatch(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	newRatings := c.rate(gi, m)
+	for _, t := range m.Teams {
+		for _, p := range t.Players() {
+			r, ok := newRatings[p]
+			if !ok || !c.InPlacement(p) {
+				continue
+			}
+			shown := math.Max(c.Shown(p, t.PlayerRating(p)), r.ConservativeRating())
+			if r.Stddev() >= c.PlacedStddev {
+				c.store().SetShown(p, shown)
+				continue
+			}
+			c.store().Delete(p)
+			e := Event{Player: p, Rating: r, Shown: shown}
+			if c.Ladder != nil {
+				e.Placement = c.Ladder.Place(r)
+			}
+			c.Placed = append(c.Placed, e)
+			if c.OnPlaced != nil {
+				c.OnPlaced(e)
+			}
+		}
+	}
+	return newRatings
+}
+
+// Calculates new ratings like CalcNewRatings without changing the placement
+// state or calling OnPlaced.
+func (c *Calc) Preview(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return c.rate(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Returns copies of the teams with the ratings a match between them is rated
+// from: players in placement get the difference of the dynamics variances up
+// front, on top of the game's dynamics the calculator adds.
+func (c *Calc) Priors(gi *skills.GameInfo, teams []skills.Team) []skills.Team {
+	return c.priors(gi, teams, 1)
+}
+
+// Returns the priors of a match of the given weight, whose dynamics, like the
+// game's, grow with the weight up to 1.
+func (c *Calc) priors(gi *skills.GameInfo, teams []skills.Team, weight float64) []skills.Team {
+	growth := math.Min(weight, 1) * (numerics.Sqr(c.DynamicsFactor) - numerics.Sqr(gi.DynamicsFactor))
+	pteams := make([]skills.Team, len(teams))
+	for i, t := range teams {
+		pteams[i] = t.MapRatings(func(p skills.Player, r skills.Rating) skills.Rating {
+			if !c.InPlacement(p) || growth <= 0 {
+				return r
+			}
+			return skills.NewRating(r.Mean(), math.Sqrt(r.Variance()+growth)).WithBeta(r.Beta()).PlayedAt(r.LastPlayed())
+		})
+	}
+	return pteams
+}
+
+// Returns the underlying calculator.
+func (c *Calc) Unwrap() skills.Calc {
+	return c.Calc
+}
+
+func (c *Calc) rate(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	pm := *m
+	pm.Teams = c.priors(gi, m.Teams, m.MatchWeight())
+	if mc, ok := c.Calc.(skills.MatchCalc); ok {
+		return mc.CalcMatch(gi, &pm)
+	}
+	return c.Calc.CalcNewRatings(gi, pm.Teams, pm.Ranks...)
+}
+
+// Calculates the match quality with the underlying calculator from the
+// priors the match would be rated from (see Priors).
+func (c *Calc) CalcMatchQual(gi *skills.GameInfo, teams []skills.Team) float64 {
+	return c.Calc.CalcMatchQual(gi, c.Priors(gi, teams))
+}
+
+// Calculates the match quality from the priors the match would be rated from
+// with the underlying calculator's skills.Match.Quality, which also takes the
+// rest of the match into account.
+func (c *Calc) MatchQual(gi *skills.GameInfo, m *skills.Match) float64 {
+	qm := *m
+	qm.Teams = c.priors(gi, m.Teams, m.MatchWeight())
+	return qm.Quality(c.Calc, gi)
+}
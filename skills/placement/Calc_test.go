@@ -0,0 +1,156 @@
+package placement
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/display"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"testing"
+)
+
+var testLadder = &display.Ladder{
+	Scale: display.Levels,
+	Tiers: []display.Tier{
+		{Name: "Bronze", Min: 0},
+		{Name: "Silver", Min: 15},
+		{Name: "Gold", Min: 30},
+	},
+}
+
+func TestPlacement(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann, bob := *skills.NewPlayer("ann"), *skills.NewPlayer("bob")
+
+	var events []Event
+	calc := NewCalc(&trueskill.TwoPlayerCalc{}, 4*gi.DynamicsFactor, 3, testLadder)
+	calc.OnPlaced = func(e Event) { events = append(events, e) }
+	calc.Begin(ann)
+
+	// Plays ann against a settled bob, who wins every given match
+	ratings := skills.PlayerRatings{ann: gi.DefaultRating(), bob: skills.NewRating(30, 1)}
+	play := func(bobWins bool) {
+		team1 := skills.NewTeam()
+		team1.AddPlayer(ann, ratings[ann])
+		team2 := skills.NewTeam()
+		team2.AddPlayer(bob, ratings[bob])
+		ranks := []int{1, 2}
+		if bobWins {
+			ranks = []int{2, 1}
+		}
+		for p, r := range calc.CalcNewRatings(gi, []skills.Team{team1, team2}, ranks...) {
+			ratings[p] = r
+		}
+	}
+
+	// Placement moves ratings further than usual
+	team1 := skills.NewTeam()
+	team1.AddPlayer(ann, ratings[ann])
+	team2 := skills.NewTeam()
+	team2.AddPlayer(bob, ratings[bob])
+	plain := (&trueskill.TwoPlayerCalc{}).CalcNewRatings(gi, []skills.Team{team1, team2}, 1, 2)[ann]
+	play(false)
+	if ratings[ann].Mean() <= plain.Mean() {
+		t.Errorf("mean after a placement win = %v, want more than %v", ratings[ann].Mean(), plain.Mean())
+	}
+
+	// A loss lowers the rating but not the one shown
+	shown := calc.Shown(ann, ratings[ann])
+	play(true)
+	if ratings[ann].ConservativeRating() >= shown || calc.Shown(ann, ratings[ann]) != shown {
+		t.Errorf("after a loss rating %v shown as %v, want %v", ratings[ann].ConservativeRating(), calc.Shown(ann, ratings[ann]), shown)
+	}
+
+	for i := 0; i < 30 && calc.InPlacement(ann); i++ {
+		play(i%3 == 2)
+	}
+	if calc.InPlacement(ann) || len(events) != 1 || len(calc.Placed) != 1 || calc.Placed[0] != events[0] {
+		t.Fatalf("ann in placement = %v after %v placed events, recorded %+v", calc.InPlacement(ann), len(events), calc.Placed)
+	}
+	if e := events[0]; e.Player != ann || e.Rating.Stddev() >= 3 || e.Placement != testLadder.Place(e.Rating) || e.Shown < e.Rating.ConservativeRating() {
+		t.Errorf("placed event = %+v", e)
+	}
+
+	// Placed players are rated as usual and placed only once
+	play(false)
+	if len(events) != 1 || calc.Shown(ann, ratings[ann]) != ratings[ann].ConservativeRating() {
+		t.Errorf("after placement %v events and rating shown as %v", len(events), calc.Shown(ann, ratings[ann]))
+	}
+}
+
+func TestPlacementStore(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann, bob := *skills.NewPlayer("ann"), *skills.NewPlayer("bob")
+	team1 := skills.NewTeam()
+	team1.AddPlayer(ann, gi.DefaultRating())
+	team2 := skills.NewTeam()
+	team2.AddPlayer(bob, skills.NewRating(30, 1))
+	teams := []skills.Team{team1, team2}
+
+	// A literal without a ladder works, and its state can be restored into a
+	// new calculator
+	var events []Event
+	calc := &Calc{Calc: &trueskill.TwoPlayerCalc{}, DynamicsFactor: 4 * gi.DynamicsFactor, PlacedStddev: 5}
+	calc.OnPlaced = func(e Event) { events = append(events, e) }
+	calc.Begin(ann)
+	won := calc.CalcNewRatings(gi, teams, 1, 2)[ann]
+
+	saved := calc.Store.(ShownRatings)
+	restored := &Calc{Calc: &trueskill.TwoPlayerCalc{}, DynamicsFactor: 4 * gi.DynamicsFactor, PlacedStddev: 5, Store: ShownRatings{}}
+	for p, shown := range saved {
+		restored.Store.SetShown(p, shown)
+	}
+	if !restored.InPlacement(ann) || restored.InPlacement(bob) || restored.Shown(ann, gi.DefaultRating()) != calc.Shown(ann, gi.DefaultRating()) {
+		t.Errorf("restored state = %v, want %v", restored.Store, saved)
+	}
+
+	// Placing needs no ladder
+	team1.AddPlayer(ann, won)
+	restored.OnPlaced = calc.OnPlaced
+	for i := 0; i < 30 && restored.InPlacement(ann); i++ {
+		newRatings := restored.CalcNewRatings(gi, teams, 1, 2)
+		team1.AddPlayer(ann, newRatings[ann])
+		team2.AddPlayer(bob, newRatings[bob])
+	}
+	if len(events) != 1 || events[0].Placement != (display.Placement{}) {
+		t.Errorf("placed events without a ladder = %+v", events)
+	}
+}
+
+func TestPlacementPriors(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann, bob := *skills.NewPlayer("ann"), *skills.NewPlayer("bob")
+	team1 := skills.NewTeam()
+	team1.AddPlayer(ann, gi.DefaultRating())
+	team2 := skills.NewTeam()
+	team2.AddPlayer(bob, skills.NewRating(30, 1))
+	teams := []skills.Team{team1, team2}
+
+	calc := &Calc{Calc: &trueskill.TwoPlayerCalc{}, DynamicsFactor: 4 * gi.DynamicsFactor, PlacedStddev: 100}
+	calc.Begin(ann)
+
+	// The match quality is that of the grown priors
+	priors := calc.Priors(gi, teams)
+	if q, want := calc.CalcMatchQual(gi, teams), (&trueskill.TwoPlayerCalc{}).CalcMatchQual(gi, priors); q != want || q == (&trueskill.TwoPlayerCalc{}).CalcMatchQual(gi, teams) {
+		t.Errorf("match quality = %v, want %v from the grown priors", q, want)
+	}
+
+	// A match of half weight grows ann's variance by half as much
+	m := skills.NewMatch(teams, 1, 2)
+	half := 0.5
+	m.Weight = &half
+	grown := skills.NewRating(25, math.Sqrt(gi.DefaultRating().Variance()+half*(16-1)*gi.DynamicsFactor*gi.DynamicsFactor))
+	team1 = skills.NewTeam()
+	team1.AddPlayer(ann, grown)
+	wm := *m
+	wm.Teams = []skills.Team{team1, team2}
+	want := (&trueskill.TwoPlayerCalc{}).CalcMatch(gi, &wm)[ann]
+
+	// and placing is recorded without OnPlaced
+	got := calc.CalcMatch(gi, m)[ann]
+	if !got.Equal(want) {
+		t.Errorf("rating after a match of half weight = %v, want %v", got, want)
+	}
+	if calc.InPlacement(ann) || len(calc.Placed) != 1 || calc.Placed[0].Player != ann || !calc.Placed[0].Rating.Equal(got) {
+		t.Errorf("placed without OnPlaced = %+v", calc.Placed)
+	}
+}
@@ -0,0 +1,67 @@
+package roles
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+)
+
+// Calc rates matches with the role ratings of a store. The ratings already on
+// the teams are ignored; each player's prior is taken from the store for the
+// role they play, and CalcNewRatings writes the new ratings back to the store.
+type Calc struct {
+	Calc  skills.Calc
+	Store *Store
+}
+
+// Calculates new ratings for the roles the players played and stores them.
+func (c *Calc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return c.CalcMatch(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Calculates new ratings for the roles the players played and stores them.
+// The match is passed on to the underlying calculator if it is a
+// skills.MatchCalc.
+func (c *Calc) CalcMatch(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	newRatings := c.rate(gi, m)
+	c.Store.Update(m.Teams, newRatings)
+	return newRatings
+}
+
+// Calculates new ratings for the roles the players played like
+// CalcNewRatings, without storing them.
+func (c *Calc) Preview(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return c.rate(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Returns copies of the teams with each player's prior for their role, the
+// ratings a match between them is rated from.
+func (c *Calc) Priors(gi *skills.GameInfo, teams []skills.Team) []skills.Team {
+	return c.Store.Teams(gi, teams)
+}
+
+// Returns the underlying calculator.
+func (c *Calc) Unwrap() skills.Calc {
+	return c.Calc
+}
+
+func (c *Calc) rate(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	rm := *m
+	rm.Teams = c.Priors(gi, m.Teams)
+	if mc, ok := c.Calc.(skills.MatchCalc); ok {
+		return mc.CalcMatch(gi, &rm)
+	}
+	return c.Calc.CalcNewRatings(gi, rm.Teams, rm.Ranks...)
+}
+
+// Calculates the match quality from the players' ratings for their roles.
+func (c *Calc) CalcMatchQual(gi *skills.GameInfo, teams []skills.Team) float64 {
+	return c.Calc.CalcMatchQual(gi, c.Priors(gi, teams))
+}
+
+// Calculates the match quality from the same priors as CalcMatchQual with
+// the underlying calculator's skills.Match.Quality, which also takes the rest
+// of the match into account.
+func (c *Calc) MatchQual(gi *skills.GameInfo, m *skills.Match) float64 {
+	qm := *m
+	qm.Teams = c.Priors(gi, m.Teams)
+	return qm.Quality(c.Calc, gi)
+}
@@ -0,0 +1,121 @@
+// Package roles rates players separately for each role they play.
+//
+// A player's skill as a tank says little about their skill as a healer, so
+// each player holds one rating per role. The role a player played in a match
+// is given by the Role attribute of the player on their team (see
+// skills.Team.AddPlayerAttrs); only that role's rating is used for the match
+// and only that rating is updated.
+package roles
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"math"
+	"sort"
+)
+
+// A Store holds a rating per role for each player.
+type Store struct {
+	ratings map[skills.Player]map[string]skills.Rating
+
+	// If set, the prior of a player for a role they have not played yet is
+	// seeded from their ratings in the roles they have played, rather than
+	// being the default rating.
+	SeedFromOtherRoles bool
+
+	// Variance added to a seeded prior to reflect how much less is known
+	// about a new role; 0 means a quarter of the default rating's variance.
+	// The variance of a seeded prior is capped at that of the default rating.
+	SeedVariance float64
+}
+
+func NewStore() *Store {
+	return &Store{ratings: make(map[skills.Player]map[string]skills.Rating)}
+}
+
+// Returns the rating of a player in a role and whether the player has played it.
+func (s *Store) Rating(p skills.Player, role string) (skills.Rating, bool) {
+	r, ok := s.ratings[p][role]
+	return r, ok
+}
+
+func (s *Store) SetRating(p skills.Player, role string, r skills.Rating) {
+	rs, ok := s.ratings[p]
+	if !ok {
+		rs = make(map[string]skills.Rating)
+		s.ratings[p] = rs
+	}
+	rs[role] = r
+}
+
+// Returns the roles a player has a rating for, in sorted order.
+func (s *Store) Roles(p skills.Player) []string {
+	roles := []string{}
+	for role := range s.ratings[p] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// Returns the prior of a player for a match in a role: the player's rating in
+// the role if they have one, otherwise a seeded or default rating.
+func (s *Store) Prior(gi *skills.GameInfo, p skills.Player, role string) skills.Rating {
+	if r, ok := s.Rating(p, role); ok {
+		return r
+	}
+	if !s.SeedFromOtherRoles || len(s.ratings[p]) == 0 {
+		return gi.DefaultRating()
+	}
+
+	// Center the prior on the other roles weighted by precision, but keep it
+	// at least as uncertain as the loosest of them: they are measurements of
+	// related skills, not independent measurements of the same one. Roles
+	// known exactly outweigh all others.
+	var precision, precisionMean, exactSum, maxVariance float64
+	exact := 0
+	for _, role := range s.Roles(p) {
+		r := s.ratings[p][role]
+		maxVariance = math.Max(maxVariance, r.Variance())
+		if r.Variance() == 0 {
+			exact++
+			exactSum += r.Mean()
+			continue
+		}
+		precision += 1 / r.Variance()
+		precisionMean += r.Mean() / r.Variance()
+	}
+	mean := precisionMean / precision
+	if exact > 0 {
+		mean = exactSum / float64(exact)
+	}
+
+	seed := s.SeedVariance
+	if seed == 0 {
+		seed = gi.DefaultRating().Variance() / 4
+	}
+	variance := math.Min(maxVariance+seed, gi.DefaultRating().Variance())
+	return skills.NewRating(mean, math.Sqrt(variance))
+}
+
+// Returns copies of the teams with each player's rating replaced by their
+// prior for the role they play on the team.
+func (s *Store) Teams(gi *skills.GameInfo, teams []skills.Team) []skills.Team {
+	rteams := make([]skills.Team, len(teams))
+	for i, t := range teams {
+		rteams[i] = t.MapRatings(func(p skills.Player, r skills.Rating) skills.Rating {
+			return s.Prior(gi, p, t.Attrs(p).Role)
+		})
+	}
+	return rteams
+}
+
+// Stores the new ratings from a match under the role each player played.
+func (s *Store) Update(teams []skills.Team, newRatings skills.PlayerRatings) {
+	for _, t := range teams {
+		for _, p := range t.Players() {
+			if r, ok := newRatings[p]; ok {
+				s.SetRating(p, t.Attrs(p).Role, r)
+			}
+		}
+	}
+}
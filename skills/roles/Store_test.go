@@ -0,0 +1,94 @@
+package roles
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"testing"
+)
+
+func TestCalcUpdatesOnlyPlayedRole(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann := *skills.NewPlayer("ann")
+	bob := *skills.NewPlayer("bob")
+
+	store := NewStore()
+	store.SetRating(ann, "tank", skills.NewRating(30, 2))
+	store.SetRating(ann, "healer", skills.NewRating(15, 3))
+	calc := &Calc{&trueskill.TwoTeamCalc{}, store}
+
+	team1 := skills.NewTeam()
+	team1.AddPlayerAttrs(ann, gi.DefaultRating(), skills.PlayerAttrs{Weight: 1, Role: "healer"})
+	team2 := skills.NewTeam()
+	team2.AddPlayerAttrs(bob, gi.DefaultRating(), skills.PlayerAttrs{Weight: 1, Role: "tank"})
+	teams := []skills.Team{team1, team2}
+
+	// Ann's healer rating is used, so the match looks lopsided
+	healerQual := calc.CalcMatchQual(gi, teams)
+	if plain := (&trueskill.TwoTeamCalc{}).CalcMatchQual(gi, teams); healerQual >= plain {
+		t.Errorf("match quality with role ratings = %v, want less than %v", healerQual, plain)
+	}
+
+	// Previewing a match stores nothing
+	preview := calc.Preview(gi, teams, 1, 2)
+	if r, _ := store.Rating(ann, "healer"); !r.Equal(skills.NewRating(15, 3)) {
+		t.Errorf("ann's healer rating after a preview = %v, want it unchanged", r)
+	}
+
+	newRatings := calc.CalcNewRatings(gi, teams, 1, 2)
+	if !preview[ann].Equal(newRatings[ann]) {
+		t.Errorf("ann's previewed rating = %v, want %v", preview[ann], newRatings[ann])
+	}
+
+	if r, _ := store.Rating(ann, "healer"); !r.Equal(newRatings[ann]) || r.Mean() <= 15 {
+		t.Errorf("ann's healer rating = %v, want %v above 15", r, newRatings[ann])
+	}
+	if r, _ := store.Rating(ann, "tank"); !r.Equal(skills.NewRating(30, 2)) {
+		t.Errorf("ann's tank rating = %v, want it unchanged", r)
+	}
+	if r, ok := store.Rating(bob, "tank"); !ok || !r.Equal(newRatings[bob]) {
+		t.Errorf("bob's tank rating = %v, %v, want %v", r, ok, newRatings[bob])
+	}
+	if roles := store.Roles(ann); len(roles) != 2 || roles[0] != "healer" || roles[1] != "tank" {
+		t.Errorf("ann's roles = %v", roles)
+	}
+}
+
+func TestSeedFromOtherRoles(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann := *skills.NewPlayer("ann")
+
+	store := NewStore()
+	store.SetRating(ann, "tank", skills.NewRating(30, 2))
+	store.SetRating(ann, "healer", skills.NewRating(20, 2))
+
+	if r := store.Prior(gi, ann, "dps"); !r.Equal(gi.DefaultRating()) {
+		t.Errorf("unseeded prior = %v, want the default %v", r, gi.DefaultRating())
+	}
+
+	store.SeedFromOtherRoles = true
+	store.SeedVariance = 4
+	r := store.Prior(gi, ann, "dps")
+	if math.Abs(r.Mean()-25) > 1e-9 || math.Abs(r.Variance()-8) > 1e-9 {
+		t.Errorf("seeded prior = %v, want {μ:25 σ²:8}", r)
+	}
+
+	// The prior is as loose as the loosest role, and by default looser still
+	store.SeedVariance = 0
+	store.SetRating(ann, "healer", skills.NewRating(20, 4))
+	want := gi.DefaultRating().Variance()/4 + 16
+	if r := store.Prior(gi, ann, "dps"); math.Abs(r.Mean()-28) > 1e-9 || math.Abs(r.Variance()-want) > 1e-9 {
+		t.Errorf("seeded prior = %v, want {μ:28 σ²:%v}", r, want)
+	}
+
+	// Exact roles make for a finite prior
+	store.SetRating(ann, "tank", skills.NewRating(30, 0))
+	if r := store.Prior(gi, ann, "dps"); r.Mean() != 30 || math.Abs(r.Variance()-want) > 1e-9 {
+		t.Errorf("prior seeded from an exact role = %v, want {μ:30 σ²:%v}", r, want)
+	}
+
+	store.SeedVariance = 1000
+	if r := store.Prior(gi, ann, "dps"); math.Abs(r.Stddev()-gi.InitialStddev) > 1e-9 {
+		t.Errorf("seeded prior = %v, want σ capped at %v", r, gi.InitialStddev)
+	}
+}
@@ -0,0 +1,167 @@
+// Package season resets ratings at season boundaries and keeps the ratings
+// of past seasons.
+//
+// A soft reset pulls every rating toward the mean of the population and makes
+// it less certain, so players have to prove themselves again each season
+// without starting over.
+package season
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"math"
+	"time"
+)
+
+// A Store holds ratings that can be listed, as needed to reset them.
+// skills.PlayerRatings is a Store.
+type Store interface {
+	skills.RatingStore
+
+	// Returns the players with a rating.
+	Players() []skills.Player
+}
+
+// A soft reset of the ratings in a store.
+type Reset struct {
+	// The fraction of the distance to the population mean taken off every
+	// mean: 0 keeps the means and 1 moves them all to the population mean.
+	Shrink float64
+
+	// Variance added to every rating.
+	AddedVariance float64
+
+	// Bounds on the standard deviations after the reset; zero means no bound.
+	MinStddev float64
+	MaxStddev float64
+
+	// Players who have not played for this long at the time of the reset
+	// keep their ratings and do not count in the population mean; zero
+	// exempts no one. Their ratings already grow while they are away if the
+	// game has an inactivity rate.
+	InactiveFor time.Duration
+
+	// The players whose ratings are held fixed (see skills.PlayerAttrs.Anchor),
+	// e.g. bots of known skill. Players anchored either way keep their
+	// ratings and do not count in the population mean.
+	Anchors map[skills.Player]skills.Anchor
+}
+
+// Returns whether a rating is exempt from a reset at the given time.
+func (this *Reset) Exempt(r skills.Rating, at time.Time) bool {
+	return this.InactiveFor > 0 && !r.LastPlayed().IsZero() && at.Sub(r.LastPlayed()) >= this.InactiveFor
+}
+
+// Resets the ratings of the store at the given time and returns the
+// population mean they were pulled toward, the mean of the ratings reset.
+// Exempt and anchored players are left as they are.
+func (this *Reset) Apply(s Store, at time.Time) float64 {
+	var players []skills.Player
+	var sum float64
+	for _, p := range s.Players() {
+		r, _ := s.Rating(p)
+		if this.Anchors[p] == skills.NotAnchored && !this.Exempt(r, at) {
+			players = append(players, p)
+			sum += r.Mean()
+		}
+	}
+	if len(players) == 0 {
+		return 0
+	}
+
+	mean := sum / float64(len(players))
+	for _, p := range players {
+		r, _ := s.Rating(p)
+		s.SetRating(p, this.rating(r, mean))
+	}
+	return mean
+}
+
+func (this *Reset) rating(r skills.Rating, mean float64) skills.Rating {
+	stddev := math.Sqrt(r.Variance() + this.AddedVariance)
+	if this.MinStddev > 0 {
+		stddev = math.Max(stddev, this.MinStddev)
+	}
+	if this.MaxStddev > 0 {
+		stddev = math.Min(stddev, this.MaxStddev)
+	}
+	newMean := r.Mean() - this.Shrink*(r.Mean()-mean)
+	return skills.NewRating(newMean, stddev).WithBeta(r.Beta()).PlayedAt(r.LastPlayed())
+}
+
+// A past season and the ratings at its end.
+type Season struct {
+	Name    string
+	End     time.Time
+	Ratings skills.PlayerRatings
+}
+
+// An Archive holds the ratings at the end of past seasons, in order.
+type Archive struct {
+	seasons []*Season
+}
+
+func NewArchive() *Archive {
+	return &Archive{}
+}
+
+// Ends a season at the given time: archives a copy of the ratings in the
+// store under the season's name, then applies the reset, if any, to the
+// store. Seasons must end in order and have distinct names.
+func (a *Archive) End(name string, at time.Time, s Store, reset *Reset) *Season {
+	if _, ok := a.Season(name); ok {
+		panic(fmt.Errorf("season %q already ended", name))
+	}
+	if n := len(a.seasons); n > 0 && at.Before(a.seasons[n-1].End) {
+		panic(fmt.Errorf("season %q ends at %v, before season %q", name, at, a.seasons[n-1].Name))
+	}
+
+	ratings := make(skills.PlayerRatings)
+	for _, p := range s.Players() {
+		ratings[p], _ = s.Rating(p)
+	}
+	season := &Season{name, at, ratings}
+	a.seasons = append(a.seasons, season)
+
+	if reset != nil {
+		reset.Apply(s, at)
+	}
+	return season
+}
+
+// Returns the past seasons in the order they ended.
+func (a *Archive) Seasons() []*Season {
+	return append([]*Season{}, a.seasons...)
+}
+
+// Returns the past season with the given name and whether there is one.
+func (a *Archive) Season(name string) (*Season, bool) {
+	for _, s := range a.seasons {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the past season running at the given time, the first to end after
+// it, and whether there is one.
+func (a *Archive) At(t time.Time) (*Season, bool) {
+	for _, s := range a.seasons {
+		if t.Before(s.End) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Returns a player's rating at the end of a past season and whether they had
+// one.
+func (a *Archive) Rating(season string, p skills.Player) (skills.Rating, bool) {
+	s, ok := a.Season(season)
+	if !ok {
+		return skills.Rating{}, false
+	}
+	r, ok := s.Ratings[p]
+	return r, ok
+}
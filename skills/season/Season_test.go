@@ -0,0 +1,81 @@
+package season
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReset(t *testing.T) {
+	now := time.Date(2013, 6, 1, 0, 0, 0, 0, time.UTC)
+	ann, bob, cat := *skills.NewPlayer("ann"), *skills.NewPlayer("bob"), *skills.NewPlayer("cat")
+	bot, pro := *skills.NewPlayer("bot"), *skills.NewPlayer("pro")
+
+	store := skills.PlayerRatings{
+		ann: skills.NewRating(35, 1).WithBeta(3).PlayedAt(now.AddDate(0, 0, -1)),
+		bob: skills.NewRating(15, 8).PlayedAt(now.AddDate(0, -1, 0)),
+		cat: skills.NewRating(40, 2).PlayedAt(now.AddDate(-1, 0, 0)),
+		bot: skills.NewRating(10, 0).PlayedAt(now),
+		pro: skills.NewRating(50, 1).PlayedAt(now),
+	}
+	reset := &Reset{Shrink: 0.5, AddedVariance: 12, MinStddev: 4, MaxStddev: 8.5, InactiveFor: 90 * 24 * time.Hour}
+	reset.Anchors = map[skills.Player]skills.Anchor{bot: skills.AnchoredExact, pro: skills.Anchored}
+
+	// Cat has been away too long to count, and the anchored bot and pro are
+	// held fixed
+	if mean := reset.Apply(store, now); mean != 25 {
+		t.Errorf("population mean = %v, want %v", mean, 25)
+	}
+
+	want := map[skills.Player]skills.Rating{
+		ann: skills.NewRating(30, 4),
+		bob: skills.NewRating(20, 8.5),
+		cat: skills.NewRating(40, 2),
+		bot: skills.NewRating(10, 0),
+		pro: skills.NewRating(50, 1),
+	}
+	for p, w := range want {
+		r := store[p]
+		if math.Abs(r.Mean()-w.Mean()) > 1e-9 || math.Abs(r.Stddev()-w.Stddev()) > 1e-9 {
+			t.Errorf("%v after reset = %v, want %v", p, r, w)
+		}
+	}
+	if r := store[ann]; r.Beta() != 3 || !r.LastPlayed().Equal(now.AddDate(0, 0, -1)) {
+		t.Errorf("ann after reset has beta %v and last played %v", r.Beta(), r.LastPlayed())
+	}
+}
+
+func TestArchive(t *testing.T) {
+	start := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	ann := *skills.NewPlayer("ann")
+	store := skills.PlayerRatings{ann: skills.NewRating(35, 1)}
+
+	archive := NewArchive()
+	archive.End("S1", start.AddDate(0, 3, 0), store, &Reset{Shrink: 1})
+	store[ann] = skills.NewRating(28, 3)
+	archive.End("S2", start.AddDate(0, 6, 0), store, nil)
+
+	if r, ok := archive.Rating("S1", ann); !ok || !r.Equal(skills.NewRating(35, 1)) {
+		t.Errorf("ann in S1 = %v, %v", r, ok)
+	}
+	if r, ok := archive.Rating("S2", ann); !ok || !r.Equal(skills.NewRating(28, 3)) {
+		t.Errorf("ann in S2 = %v, %v", r, ok)
+	}
+	if _, ok := archive.Rating("S3", ann); ok {
+		t.Errorf("ann has a rating in a season not ended yet")
+	}
+	if s, ok := archive.At(start.AddDate(0, 4, 0)); !ok || s.Name != "S2" {
+		t.Errorf("season running in May = %v, %v", s, ok)
+	}
+	if _, ok := archive.At(start.AddDate(0, 7, 0)); ok {
+		t.Errorf("found a past season running in August")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ending a season twice did not panic")
+		}
+	}()
+	archive.End("S2", start.AddDate(0, 9, 0), store, nil)
+}
@@ -0,0 +1,230 @@
+// Package stakes projects what the players of a match stand to gain or lose
+// before it is played.
+//
+// For every outcome of the match a team could see, the match is rated as if
+// it had ended that way, giving the players' ratings after each outcome along
+// with its probability. Nothing is stored: calculators keeping state of their
+// own, like roles.Calc, placement.Calc and multimode.Calc, implement
+// Previewer to rate without changing it.
+package stakes
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"sort"
+)
+
+// The number of points and the span, in standard deviations, of the grid the
+// probabilities of finishing positions are integrated over.
+const (
+	gridPoints = 801
+	gridSpan   = 8.0
+)
+
+// A Previewer is a calculator with state that rates matches with another
+// calculator, from priors of its own rather than the ratings on the teams.
+type Previewer interface {
+	// Rates a match like CalcNewRatings without changing the state or
+	// calling back.
+	Preview(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings
+
+	// Returns copies of the teams with the ratings a match between them is
+	// rated from.
+	Priors(gi *skills.GameInfo, teams []skills.Team) []skills.Team
+
+	// Returns the calculator the priors are rated with.
+	Unwrap() skills.Calc
+}
+
+// Returns the function rating the projected outcomes with the calculator and
+// the teams with the priors it rates them from.
+func previewer(calc skills.Calc, gi *skills.GameInfo, teams []skills.Team) (func(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings, []skills.Team) {
+	if p, ok := calc.(Previewer); ok {
+		return p.Preview, p.Priors(gi, teams)
+	}
+	return calc.CalcNewRatings, teams
+}
+
+// Returns the calculator predicting the outcomes of matches it rates: the
+// calculator itself or the one a Previewer rates its priors with.
+func predictor(calc skills.Calc) (skills.OutcomePredictor, bool) {
+	for {
+		if pred, ok := calc.(skills.OutcomePredictor); ok {
+			return pred, true
+		}
+		p, ok := calc.(Previewer)
+		if !ok {
+			return nil, false
+		}
+		calc = p.Unwrap()
+	}
+}
+
+// An outcome of a match for a team.
+type Outcome struct {
+	// The team's finishing position, 1 for first. A draw of two teams is a
+	// shared first place.
+	Position int
+	Draw     bool
+
+	Prob float64
+
+	// The ratings of the team's players after the outcome.
+	Ratings skills.PlayerRatings
+}
+
+// The stakes of a match for a team.
+type Stakes struct {
+	Outcomes []Outcome
+
+	// The expected change of each player's mean over the outcomes, from the
+	// prior the match is rated from.
+	Expected map[skills.Player]float64
+}
+
+// Returns the stakes of a match between the teams for each team, in the order
+// of the teams.
+//
+// Probabilities and expected changes are those of the priors the calculator
+// rates the match from (see Previewer). If there are two teams and the
+// calculator, or the one a Previewer wraps, is a skills.OutcomePredictor, the
+// outcomes are a win, a draw and a loss with its probabilities. Otherwise the
+// outcomes are the finishing positions without ties. A team finishing in a
+// position is rated with the other teams filling the other positions in order
+// of their mean sums, and the probability of the position is that of the
+// team's performance having exactly that many better ones. Like FFACalc these
+// probabilities take no advantage of the first team into account, and unlike
+// it neither the curve nor draws: the performances are Gaussian and never tie.
+func Project(calc skills.Calc, gi *skills.GameInfo, teams []skills.Team) []*Stakes {
+	rate, priors := previewer(calc, gi, teams)
+	if pred, ok := predictor(calc); ok && len(teams) == 2 {
+		return projectTwoTeams(rate, pred, gi, teams, priors)
+	}
+
+	stakes := make([]*Stakes, len(teams))
+	probs := positionProbs(gi, priors)
+	for i, t := range teams {
+		s := &Stakes{}
+		for k := range teams {
+			ratings := rate(gi, teams, positionRanks(priors, i, k)...)
+			s.Outcomes = append(s.Outcomes, Outcome{Position: k + 1, Prob: probs[i][k], Ratings: teamRatings(t, ratings)})
+		}
+		s.expect(priors[i])
+		stakes[i] = s
+	}
+	return stakes
+}
+
+func projectTwoTeams(rate func(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings, pred skills.OutcomePredictor, gi *skills.GameInfo, teams, priors []skills.Team) []*Stakes {
+	win, draw, lose := pred.CalcOutcomeProbs(gi, priors)
+	won := rate(gi, teams, 1, 2)
+	drew := rate(gi, teams, 1, 1)
+	lost := rate(gi, teams, 2, 1)
+
+	first := &Stakes{Outcomes: []Outcome{
+		{Position: 1, Prob: win, Ratings: teamRatings(teams[0], won)},
+		{Position: 1, Draw: true, Prob: draw, Ratings: teamRatings(teams[0], drew)},
+		{Position: 2, Prob: lose, Ratings: teamRatings(teams[0], lost)},
+	}}
+	second := &Stakes{Outcomes: []Outcome{
+		{Position: 1, Prob: lose, Ratings: teamRatings(teams[1], lost)},
+		{Position: 1, Draw: true, Prob: draw, Ratings: teamRatings(teams[1], drew)},
+		{Position: 2, Prob: win, Ratings: teamRatings(teams[1], won)},
+	}}
+	first.expect(priors[0])
+	second.expect(priors[1])
+	return []*Stakes{first, second}
+}
+
+// Sets the expected changes of the players of the team, which holds their priors.
+func (s *Stakes) expect(t skills.Team) {
+	s.Expected = make(map[skills.Player]float64)
+	for _, o := range s.Outcomes {
+		for _, p := range t.Players() {
+			s.Expected[p] += o.Prob * (o.Ratings[p].Mean() - t.PlayerRating(p).Mean())
+		}
+	}
+}
+
+// Returns the ratings of the team's players.
+func teamRatings(t skills.Team, ratings skills.PlayerRatings) skills.PlayerRatings {
+	tr := make(skills.PlayerRatings)
+	for _, p := range t.Players() {
+		tr[p] = ratings[p]
+	}
+	return tr
+}
+
+// Returns ranks placing team i in position k (from 0) and the other teams in
+// the other positions in order of their mean sums.
+func positionRanks(teams []skills.Team, i, k int) []int {
+	var others []int
+	for j := range teams {
+		if j != i {
+			others = append(others, j)
+		}
+	}
+	sort.SliceStable(others, func(a, b int) bool {
+		return teams[others[a]].Accum(skills.MeanSum) > teams[others[b]].Accum(skills.MeanSum)
+	})
+
+	ranks := make([]int, len(teams))
+	ranks[i] = k + 1
+	for n, j := range others {
+		ranks[j] = n + 1
+		if n >= k {
+			ranks[j]++
+		}
+	}
+	return ranks
+}
+
+// Returns the probabilities of each team finishing in each position. Given
+// the performance x of team i, the other teams beat it independently, each
+// with probability P(perf_j > x), so the number of teams beating it follows
+// a Poisson binomial distribution; this is integrated over x.
+func positionProbs(gi *skills.GameInfo, teams []skills.Team) [][]float64 {
+	n := len(teams)
+	means := make([]float64, n)
+	stddevs := make([]float64, n)
+	for i, t := range teams {
+		means[i] = t.Accum(skills.MeanSum)
+		stddevs[i] = math.Sqrt(t.Accum(skills.VarianceSum) + t.Accum(gi.PerformanceVarianceSum))
+	}
+
+	probs := make([][]float64, n)
+	beaten := make([]float64, n)
+	dz := 2 * gridSpan / (gridPoints - 1)
+	for i := range teams {
+		probs[i] = make([]float64, n)
+		for g := 0; g < gridPoints; g++ {
+			z := -gridSpan + float64(g)*dz
+			x := means[i] + stddevs[i]*z
+
+			// beaten[k] is the probability that exactly k teams beat x
+			for k := range beaten {
+				beaten[k] = 0
+			}
+			beaten[0] = 1
+			count := 0
+			for j := range teams {
+				if j == i {
+					continue
+				}
+				q := numerics.GaussCumulativeTo((means[j] - x) / stddevs[j])
+				count++
+				for k := count; k > 0; k-- {
+					beaten[k] = beaten[k]*(1-q) + beaten[k-1]*q
+				}
+				beaten[0] *= 1 - q
+			}
+
+			weight := numerics.GaussAt(z) * dz
+			for k := range beaten {
+				probs[i][k] += weight * beaten[k]
+			}
+		}
+	}
+	return probs
+}
@@ -0,0 +1,170 @@
+package stakes
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/multimode"
+	"github.com/ChrisHines/GoSkills/skills/placement"
+	"github.com/ChrisHines/GoSkills/skills/roles"
+	"github.com/ChrisHines/GoSkills/skills/trueskill"
+	"math"
+	"testing"
+)
+
+func TestProjectTwoTeams(t *testing.T) {
+	calc := &trueskill.TwoTeamCalc{}
+	gi := skills.DefaultGameInfo
+	ann, bob, cat := *skills.NewPlayer("ann"), *skills.NewPlayer("bob"), *skills.NewPlayer("cat")
+
+	team1 := skills.NewTeam()
+	team1.AddPlayer(ann, skills.NewRating(25, 8))
+	team1.AddPlayer(bob, skills.NewRating(20, 3))
+	team2 := skills.NewTeam()
+	team2.AddPlayer(cat, skills.NewRating(40, 5))
+	teams := []skills.Team{team1, team2}
+
+	stakes := Project(calc, gi, teams)
+	win, draw, lose := calc.CalcOutcomeProbs(gi, teams)
+	lost := calc.CalcNewRatings(gi, teams, 2, 1)
+
+	first := stakes[0].Outcomes
+	if first[0].Prob != win || first[1].Prob != draw || !first[1].Draw || first[2].Prob != lose || first[2].Position != 2 {
+		t.Errorf("first team outcomes = %+v", first)
+	}
+	if !first[2].Ratings[ann].Equal(lost[ann]) || !first[2].Ratings[bob].Equal(lost[bob]) || len(first[2].Ratings) != 2 {
+		t.Errorf("first team ratings after a loss = %v, want those of %v", first[2].Ratings, lost)
+	}
+	if c := stakes[1].Outcomes[0]; c.Prob != lose || !c.Ratings[cat].Equal(lost[cat]) {
+		t.Errorf("second team's win = %+v", c)
+	}
+
+	var expected float64
+	for _, o := range stakes[1].Outcomes {
+		expected += o.Prob * (o.Ratings[cat].Mean() - 40)
+	}
+	if math.Abs(stakes[1].Expected[cat]-expected) > 1e-12 {
+		t.Errorf("cat's expected change = %v, want %v", stakes[1].Expected[cat], expected)
+	}
+
+	// A win is worth more to the underdogs than to the favourite
+	gain := func(s *Stakes, p skills.Player, r skills.Rating) float64 {
+		return s.Outcomes[0].Ratings[p].Mean() - r.Mean()
+	}
+	if a, c := gain(stakes[0], ann, team1.PlayerRating(ann)), gain(stakes[1], cat, team2.PlayerRating(cat)); a <= c {
+		t.Errorf("gain on a win of ann = %v, of cat = %v", a, c)
+	}
+}
+
+func TestProjectPositions(t *testing.T) {
+	calc := &trueskill.FFACalc{}
+	gi := skills.DefaultGameInfo
+
+	var teams []skills.Team
+	for i := 0; i < 4; i++ {
+		team := skills.NewTeam()
+		team.AddPlayer(*skills.NewPlayer(i), gi.DefaultRating())
+		teams = append(teams, team)
+	}
+
+	// Equal players are equally likely to finish anywhere
+	for i, s := range Project(calc, gi, teams) {
+		p := *skills.NewPlayer(i)
+		for k, o := range s.Outcomes {
+			if o.Position != k+1 || math.Abs(o.Prob-0.25) > 1e-6 {
+				t.Errorf("player %v position %v has probability %v", i, o.Position, o.Prob)
+			}
+			if k > 0 && o.Ratings[p].Mean() >= s.Outcomes[k-1].Ratings[p].Mean() {
+				t.Errorf("player %v gains more in position %v than in position %v", i, k+1, k)
+			}
+		}
+		if math.Abs(s.Expected[p]) > 1e-6 {
+			t.Errorf("player %v expected change = %v", i, s.Expected[p])
+		}
+	}
+
+	// A stronger player is likelier to finish first, and still expects
+	// about no change, as the ratings already predict the outcome
+	teams[0].AddPlayer(*skills.NewPlayer(0), skills.NewRating(35, 3))
+	s := Project(calc, gi, teams)[0]
+	sum := 0.0
+	for _, o := range s.Outcomes {
+		sum += o.Prob
+	}
+	if math.Abs(sum-1) > 1e-6 || s.Outcomes[0].Prob <= 0.5 || math.Abs(s.Expected[*skills.NewPlayer(0)]) > 0.1 {
+		t.Errorf("strong player outcomes = %+v, expected change %v", s.Outcomes, s.Expected)
+	}
+}
+
+func TestProjectStatefulCalc(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	calc := placement.NewCalc(&trueskill.FFACalc{}, 4*gi.DynamicsFactor, 100, nil)
+	placed := 0
+	calc.OnPlaced = func(placement.Event) { placed++ }
+
+	var teams []skills.Team
+	for i := 0; i < 3; i++ {
+		team := skills.NewTeam()
+		team.AddPlayer(*skills.NewPlayer(i), gi.DefaultRating())
+		teams = append(teams, team)
+		calc.Begin(*skills.NewPlayer(i))
+	}
+
+	// Projecting neither places anyone nor moves the ratings shown, but
+	// rates the players in placement as their matches would
+	s := Project(calc, gi, teams)
+	if placed != 0 || !calc.InPlacement(*skills.NewPlayer(0)) || calc.Shown(*skills.NewPlayer(0), gi.DefaultRating()) != gi.DefaultRating().ConservativeRating() {
+		t.Errorf("projecting changed the placement state: %v placed", placed)
+	}
+	want := calc.Preview(gi, teams, 1, 2, 3)[*skills.NewPlayer(0)]
+	if got := s[0].Outcomes[0].Ratings[*skills.NewPlayer(0)]; !got.Equal(want) {
+		t.Errorf("first place rating = %v, want %v", got, want)
+	}
+}
+
+func TestProjectAdvantage(t *testing.T) {
+	gi := *skills.DefaultGameInfo
+	var teams []skills.Team
+	for i := 0; i < 3; i++ {
+		team := skills.NewTeam()
+		team.AddPlayer(*skills.NewPlayer(i), gi.DefaultRating())
+		teams = append(teams, team)
+	}
+
+	// FFACalc rates with no advantage, so the positions have none either
+	gi.Advantage = 5
+	if p := Project(&trueskill.FFACalc{}, &gi, teams)[0].Outcomes[0].Prob; math.Abs(p-1.0/3) > 1e-6 {
+		t.Errorf("probability of first place with an advantage = %v, want 1/3", p)
+	}
+}
+
+func TestProjectWrappedPriors(t *testing.T) {
+	gi := skills.DefaultGameInfo
+	ann, bob := *skills.NewPlayer("ann"), *skills.NewPlayer("bob")
+	team1 := skills.NewTeam()
+	team1.AddPlayerAttrs(ann, gi.DefaultRating(), skills.PlayerAttrs{Weight: 1, Role: "healer"})
+	team2 := skills.NewTeam()
+	team2.AddPlayerAttrs(bob, gi.DefaultRating(), skills.PlayerAttrs{Weight: 1, Role: "tank"})
+	teams := []skills.Team{team1, team2}
+
+	// The stored role ratings are the priors, and the wrapped calculator
+	// predicts the outcomes, draws included
+	store := roles.NewStore()
+	store.SetRating(ann, "healer", skills.NewRating(15, 1))
+	calc := &roles.Calc{Calc: &trueskill.TwoTeamCalc{}, Store: store}
+	priors := store.Teams(gi, teams)
+	s := Project(calc, gi, teams)
+	win, draw, _ := (&trueskill.TwoTeamCalc{}).CalcOutcomeProbs(gi, priors)
+	if o := s[0].Outcomes; o[0].Prob != win || !o[1].Draw || o[1].Prob != draw {
+		t.Errorf("ann's outcomes = %+v, want probabilities %v, %v", o, win, draw)
+	}
+	if e := s[0].Expected[ann]; math.Abs(e) > 0.5 {
+		t.Errorf("ann's expected change = %v, want about none", e)
+	}
+
+	// Projecting a mode leaves the model as it was
+	model := multimode.NewUniformModel(gi, []string{"a", "b"}, 0.5)
+	before := model.Rating(ann, "a")
+	Project(&multimode.Calc{Calc: &trueskill.TwoTeamCalc{}, Model: model, Mode: "a"}, gi, teams)
+	if r := model.Rating(ann, "a"); !r.Equal(before) {
+		t.Errorf("ann's rating in the mode after projecting = %v, want %v", r, before)
+	}
+}
@@ -0,0 +1,187 @@
+package trueskill
+
+import (
+	"math"
+	"sync"
+)
+
+// Tables of the Gaussian truncation corrections for GameInfo.TabulatedCorrections.
+//
+// The corrections of a win or loss depend only on t = perfDiff - drawMargin
+// and are interpolated with cubic Hermite splines through their exact values
+// and derivatives. The corrections of a draw depend on |perfDiff| and
+// drawMargin and are interpolated with bicubic Hermite patches, whose
+// derivatives are found by central differences when the tables are built.
+// The interpolation error of a cubic Hermite spline is at most h⁴/384 times
+// the largest fourth derivative over the step h, which keeps the tables
+// within tableTolerance of the exact functions. Arguments outside the tables
+// fall back to the exact functions; the tables stop where the exact functions
+// start losing precision in the tails of the Gaussian.
+const (
+	tableTolerance = 1e-6
+
+	exceedsLo   = -5.0
+	exceedsHi   = 8.0
+	exceedsStep = 1.0 / 64
+
+	withinPerfDiffHi   = 4.0
+	withinPerfDiffStep = 1.0 / 32
+	withinMarginLo     = 1.0 / 128
+	withinMarginHi     = 2.0
+	withinMarginStep   = 1.0 / 64
+
+	// The step of the central differences
+	diffStep = 1e-4
+)
+
+var correctionTables struct {
+	once               sync.Once
+	vExceeds, wExceeds *hermiteTable
+	vWithin, wWithin   *hermiteTable2
+}
+
+// Builds the tables on first use, so programs not using them pay nothing.
+func buildCorrectionTables() {
+	correctionTables.once.Do(func() {
+		v := func(t float64) float64 { return vExceedsMargin(t, 0) }
+		w := func(t float64) float64 { return wExceedsMargin(t, 0) }
+
+		// v' = -w and w' = v(1 - w) - w(v + t)
+		correctionTables.vExceeds = newHermiteTable(exceedsLo, exceedsHi, exceedsStep, v, func(t float64) float64 { return -w(t) })
+		correctionTables.wExceeds = newHermiteTable(exceedsLo, exceedsHi, exceedsStep, w, func(t float64) float64 {
+			vt, wt := v(t), w(t)
+			return vt*(1-wt) - wt*(vt+t)
+		})
+
+		correctionTables.vWithin = newHermiteTable2(0, withinPerfDiffHi, withinPerfDiffStep, withinMarginLo, withinMarginHi, withinMarginStep, vWithinMargin)
+		correctionTables.wWithin = newHermiteTable2(0, withinPerfDiffHi, withinPerfDiffStep, withinMarginLo, withinMarginHi, withinMarginStep, wWithinMargin)
+	})
+}
+
+// Tabulated versions of vExceedsMargin, wExceedsMargin, vWithinMargin and
+// wWithinMargin.
+func vExceedsMarginTable(perfDiff, drawMargin float64) float64 {
+	buildCorrectionTables()
+	if v, ok := correctionTables.vExceeds.at(perfDiff - drawMargin); ok {
+		return v
+	}
+	return vExceedsMargin(perfDiff, drawMargin)
+}
+
+func wExceedsMarginTable(perfDiff, drawMargin float64) float64 {
+	buildCorrectionTables()
+	if w, ok := correctionTables.wExceeds.at(perfDiff - drawMargin); ok {
+		return w
+	}
+	return wExceedsMargin(perfDiff, drawMargin)
+}
+
+func vWithinMarginTable(perfDiff, drawMargin float64) float64 {
+	buildCorrectionTables()
+	if v, ok := correctionTables.vWithin.at(math.Abs(perfDiff), drawMargin); ok {
+		if perfDiff < 0 {
+			return -v
+		}
+		return v
+	}
+	return vWithinMargin(perfDiff, drawMargin)
+}
+
+func wWithinMarginTable(perfDiff, drawMargin float64) float64 {
+	buildCorrectionTables()
+	if w, ok := correctionTables.wWithin.at(math.Abs(perfDiff), drawMargin); ok {
+		return w
+	}
+	return wWithinMargin(perfDiff, drawMargin)
+}
+
+// A function of one variable tabulated with its derivative at evenly spaced
+// points.
+type hermiteTable struct {
+	lo, step float64
+	f, d     []float64
+}
+
+func newHermiteTable(lo, hi, step float64, f, d func(x float64) float64) *hermiteTable {
+	n := int(math.Round((hi-lo)/step)) + 1
+	t := &hermiteTable{lo, step, make([]float64, n), make([]float64, n)}
+	for i := range t.f {
+		x := lo + float64(i)*step
+		t.f[i] = f(x)
+		t.d[i] = d(x)
+	}
+	return t
+}
+
+// Returns the interpolated value at x and whether x is within the table.
+func (t *hermiteTable) at(x float64) (float64, bool) {
+	u := (x - t.lo) / t.step
+	if !(u >= 0 && u < float64(len(t.f)-1)) {
+		return 0, false
+	}
+	i := int(u)
+	s := u - float64(i)
+	h00, h10, h01, h11 := hermiteBasis(s)
+	return h00*t.f[i] + h10*t.step*t.d[i] + h01*t.f[i+1] + h11*t.step*t.d[i+1], true
+}
+
+// A function of two variables tabulated with its partial derivatives on an
+// evenly spaced grid, stored row by row of y.
+type hermiteTable2 struct {
+	xlo, xstep, ylo, ystep float64
+	nx, ny                 int
+	f, fx, fy, fxy         []float64
+}
+
+func newHermiteTable2(xlo, xhi, xstep, ylo, yhi, ystep float64, f func(x, y float64) float64) *hermiteTable2 {
+	nx := int(math.Round((xhi-xlo)/xstep)) + 1
+	ny := int(math.Round((yhi-ylo)/ystep)) + 1
+	t := &hermiteTable2{xlo, xstep, ylo, ystep, nx, ny, make([]float64, nx*ny), make([]float64, nx*ny), make([]float64, nx*ny), make([]float64, nx*ny)}
+
+	h := diffStep
+	for j := 0; j < ny; j++ {
+		y := ylo + float64(j)*ystep
+		for i := 0; i < nx; i++ {
+			x := xlo + float64(i)*xstep
+			k := j*nx + i
+			t.f[k] = f(x, y)
+			t.fx[k] = (f(x+h, y) - f(x-h, y)) / (2 * h)
+			t.fy[k] = (f(x, y+h) - f(x, y-h)) / (2 * h)
+			t.fxy[k] = (f(x+h, y+h) - f(x+h, y-h) - f(x-h, y+h) + f(x-h, y-h)) / (4 * h * h)
+		}
+	}
+	return t
+}
+
+// Returns the interpolated value at (x, y) and whether it is within the table.
+func (t *hermiteTable2) at(x, y float64) (float64, bool) {
+	u := (x - t.xlo) / t.xstep
+	r := (y - t.ylo) / t.ystep
+	if !(u >= 0 && u < float64(t.nx-1) && r >= 0 && r < float64(t.ny-1)) {
+		return 0, false
+	}
+	i, j := int(u), int(r)
+	s, q := u-float64(i), r-float64(j)
+
+	// The basis functions of each corner's value and derivative, in x and y
+	xh00, xh10, xh01, xh11 := hermiteBasis(s)
+	yh00, yh10, yh01, yh11 := hermiteBasis(q)
+	xv, xd := [2]float64{xh00, xh01}, [2]float64{xh10 * t.xstep, xh11 * t.xstep}
+	yv, yd := [2]float64{yh00, yh01}, [2]float64{yh10 * t.ystep, yh11 * t.ystep}
+
+	var sum float64
+	for b := 0; b < 2; b++ {
+		for a := 0; a < 2; a++ {
+			k := (j+b)*t.nx + i + a
+			sum += t.f[k]*xv[a]*yv[b] + t.fx[k]*xd[a]*yv[b] + t.fy[k]*xv[a]*yd[b] + t.fxy[k]*xd[a]*yd[b]
+		}
+	}
+	return sum, true
+}
+
+// Returns the cubic Hermite basis functions at s in [0, 1]: those of the
+// value and derivative at 0, then those of the value and derivative at 1.
+func hermiteBasis(s float64) (h00, h10, h01, h11 float64) {
+	s2, s3 := s*s, s*s*s
+	return 2*s3 - 3*s2 + 1, s3 - 2*s2 + s, -2*s3 + 3*s2, s3 - s2
+}
@@ -0,0 +1,95 @@
+package trueskill
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCorrectionTables(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	funcs := []struct {
+		name         string
+		table, exact func(perfDiff, drawMargin float64) float64
+	}{
+		{"vExceedsMargin", vExceedsMarginTable, vExceedsMargin},
+		{"wExceedsMargin", wExceedsMarginTable, wExceedsMargin},
+		{"vWithinMargin", vWithinMarginTable, vWithinMargin},
+		{"wWithinMargin", wWithinMarginTable, wWithinMargin},
+	}
+
+	// Sample inside and around the tables
+	for n := 0; n < 200000; n++ {
+		perfDiff := 20*rnd.Float64() - 10
+		drawMargin := 2.5 * rnd.Float64()
+		for _, f := range funcs {
+			got, want := f.table(perfDiff, drawMargin), f.exact(perfDiff, drawMargin)
+			if math.Abs(got-want) > tableTolerance {
+				t.Fatalf("%v(%v, %v) = %v, want %v", f.name, perfDiff, drawMargin, got, want)
+			}
+		}
+	}
+
+	// Tabulated ratings agree with the exact ones
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.TabulatedCorrections = true
+	for n := 0; n < 100; n++ {
+		var teams []skills.Team
+		for i := 0; i < 2; i++ {
+			team := skills.NewTeam()
+			for j := 0; j < 2; j++ {
+				team.AddPlayer(*skills.NewPlayer(2*i + j), skills.NewRating(15+20*rnd.Float64(), 1+7*rnd.Float64()))
+			}
+			teams = append(teams, team)
+		}
+		ranks := [][]int{{1, 2}, {1, 1}, {2, 1}}[n%3]
+
+		exact := (&TwoTeamCalc{}).CalcNewRatings(skills.DefaultGameInfo, teams, ranks...)
+		for p, r := range (&TwoTeamCalc{}).CalcNewRatings(&gameInfo, teams, ranks...) {
+			if math.Abs(r.Mean()-exact[p].Mean()) > 1e-4 || math.Abs(r.Stddev()-exact[p].Stddev()) > 1e-4 {
+				t.Errorf("tabulated rating of %v = %v, want %v", p, r, exact[p])
+			}
+		}
+	}
+}
+
+// Arguments spread over the range seen in practice
+func benchmarkArgs() (perfDiffs, drawMargins []float64) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1024; i++ {
+		perfDiffs = append(perfDiffs, 6*rnd.Float64()-3)
+		drawMargins = append(drawMargins, 0.2*rnd.Float64())
+	}
+	return
+}
+
+func benchmarkCorrections(b *testing.B, v, w func(perfDiff, drawMargin float64) float64) {
+	perfDiffs, drawMargins := benchmarkArgs()
+	buildCorrectionTables()
+	b.ResetTimer()
+	var sum float64
+	for i := 0; i < b.N; i++ {
+		k := i % len(perfDiffs)
+		sum += v(perfDiffs[k], drawMargins[k]) + w(perfDiffs[k], drawMargins[k])
+	}
+	benchmarkSink = sum
+}
+
+var benchmarkSink float64
+
+func BenchmarkExceedsMarginExact(b *testing.B) {
+	benchmarkCorrections(b, vExceedsMargin, wExceedsMargin)
+}
+
+func BenchmarkExceedsMarginTable(b *testing.B) {
+	benchmarkCorrections(b, vExceedsMarginTable, wExceedsMarginTable)
+}
+
+func BenchmarkWithinMarginExact(b *testing.B) {
+	benchmarkCorrections(b, vWithinMargin, wWithinMargin)
+}
+
+func BenchmarkWithinMarginTable(b *testing.B) {
+	benchmarkCorrections(b, vWithinMarginTable, wWithinMarginTable)
+}
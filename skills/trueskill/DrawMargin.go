@@ -1,6 +1,7 @@
 package trueskill
 
 import (
+	"github.com/ChrisHines/GoSkills/skills"
 	"github.com/ChrisHines/GoSkills/skills/numerics"
 	"math"
 )
@@ -16,3 +17,12 @@ func drawMarginFromDrawProbability(drawProbability, beta float64) float64 {
 	// n1 and n2 are the number of players on each team
 	return numerics.GaussInvCumulativeTo((drawProbability+1)/2, 0, 1) * math.Sqrt(1+1) * beta
 }
+
+// Returns the draw margin of the game, which for its curve F satisfies
+// draw probability = 2 * F(margin/(sqrt(2)*beta)) - 1.
+func gameDrawMargin(gi *skills.GameInfo) float64 {
+	if gi.Curve == nil {
+		return drawMarginFromDrawProbability(gi.DrawProbability, gi.Beta)
+	}
+	return gi.Curve.InvCdf((gi.DrawProbability+1)/2) * math.Sqrt(1+1) * gi.Beta
+}
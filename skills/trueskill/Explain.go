@@ -0,0 +1,88 @@
+package trueskill
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"strings"
+)
+
+// Shares of the match variance (see Explanation.Share) below and above which
+// an update is described as damped or boosted by the player's uncertainty.
+const (
+	lowUncertaintyShare  = 0.1
+	highUncertaintyShare = 0.3
+)
+
+// The intermediate quantities of a player's rating update, for explaining it.
+type Explanation struct {
+	Prior     skills.Rating // The rating going into the match, after any inactivity growth
+	Posterior skills.Rating // The rating returned by the calculator
+
+	Result  int     // skills.Win, Draw or Lose, from the player's point of view
+	WinProb float64 // The probability the player's team was expected to win
+
+	MeanDelta  float64 // The mean performance difference of the winning team over the losing team
+	DrawMargin float64
+	C          float64 // The standard deviation of the performance difference
+	V, W       float64 // The truncated Gaussian corrections of the mean and variance
+
+	// The player's share of the variance of the performance difference, c².
+	// The update of a player grows with their share.
+	Share float64
+
+	// The weight of the update (see skills.Match.Weight).
+	Weight float64
+}
+
+// Returns a breakdown of the update, e.g. "expected win probability 72%, you
+// won, rating +1.20, gain damped by low uncertainty".
+func (e *Explanation) String() string {
+	parts := []string{fmt.Sprintf("expected win probability %.0f%%", 100*e.WinProb)}
+
+	switch e.Result {
+	case skills.Win:
+		parts = append(parts, "you won")
+	case skills.Lose:
+		parts = append(parts, "you lost")
+	default:
+		parts = append(parts, "you drew")
+	}
+
+	delta := e.Posterior.Mean() - e.Prior.Mean()
+	parts = append(parts, fmt.Sprintf("rating %+.2f", delta))
+
+	change := "change"
+	if delta > 0 {
+		change = "gain"
+	} else if delta < 0 {
+		change = "loss"
+	}
+	if e.Share < lowUncertaintyShare {
+		parts = append(parts, change+" damped by low uncertainty")
+	} else if e.Share > highUncertaintyShare {
+		parts = append(parts, change+" boosted by high uncertainty")
+	}
+
+	if e.Weight != 1 {
+		parts = append(parts, fmt.Sprintf("match counted at %.0f%%", 100*e.Weight))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Calculates new ratings for the players of the match like CalcMatch, and
+// explains the update of each player.
+func (calc *TwoTeamCalc) Explain(gi *skills.GameInfo, m *skills.Match) (skills.PlayerRatings, map[skills.Player]*Explanation) {
+	explain := make(map[skills.Player]*Explanation)
+	return twoTeamCalcMatch(gi, m, explain), explain
+}
+
+// Calculates new ratings for the players of the match like CalcMatch, along
+// with the priors they were updated from (see skills.UpdateCalc).
+func (calc *TwoTeamCalc) CalcUpdate(gi *skills.GameInfo, m *skills.Match) (skills.PlayerRatings, skills.PlayerRatings) {
+	newRatings, explain := calc.Explain(gi, m)
+	priors := make(skills.PlayerRatings, len(explain))
+	for p, e := range explain {
+		priors[p] = withDynamics(gi, e.Prior, e.Weight)
+	}
+	return newRatings, priors
+}
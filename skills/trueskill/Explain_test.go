@@ -0,0 +1,55 @@
+package trueskill
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	calc := &TwoTeamCalc{}
+	gameInfo := skills.DefaultGameInfo
+	ann, bob, cat := *skills.NewPlayer("ann"), *skills.NewPlayer("bob"), *skills.NewPlayer("cat")
+
+	team1 := skills.NewTeam()
+	team1.AddPlayer(ann, skills.NewRating(30, 1))
+	team1.AddPlayer(bob, skills.NewRating(25, 8))
+	team2 := skills.NewTeam()
+	team2.AddPlayer(cat, skills.NewRating(40, 3))
+	teams := []skills.Team{team1, team2}
+
+	newRatings, explain := calc.Explain(gameInfo, skills.NewMatch(teams, 1, 2))
+	plain := calc.CalcNewRatings(gameInfo, teams, 1, 2)
+	win, _, lose := calc.CalcOutcomeProbs(gameInfo, teams)
+
+	for _, p := range []skills.Player{ann, bob, cat} {
+		e := explain[p]
+		if !newRatings[p].Equal(plain[p]) || !e.Posterior.Equal(plain[p]) {
+			t.Errorf("%v: explained rating = %v, posterior %v, want %v", p, newRatings[p], e.Posterior, plain[p])
+		}
+		if math.Abs(e.MeanDelta-15) > 1e-9 {
+			t.Errorf("%v: MeanDelta = %v, want %v", p, e.MeanDelta, 15)
+		}
+		if v := e.Prior.Variance() + numerics.Sqr(gameInfo.DynamicsFactor); math.Abs(v-e.Share*e.C*e.C) > 1e-9 {
+			t.Errorf("%v: Share = %v", p, e.Share)
+		}
+	}
+	if e := explain[ann]; math.Abs(e.WinProb-win) > 1e-9 || e.Result != skills.Win {
+		t.Errorf("ann: WinProb = %v, Result = %v, want %v and a win", e.WinProb, e.Result, win)
+	}
+	if e := explain[cat]; math.Abs(e.WinProb-lose) > 1e-9 || e.Result != skills.Lose {
+		t.Errorf("cat: WinProb = %v, Result = %v", e.WinProb, e.Result)
+	}
+
+	// Delta and damping depend on each player's uncertainty
+	if s := explain[ann].String(); s != "expected win probability 90%, you won, rating +0.02, gain damped by low uncertainty" {
+		t.Errorf("ann: %v", s)
+	}
+	if s := explain[bob].String(); s != "expected win probability 90%, you won, rating +1.13, gain boosted by high uncertainty" {
+		t.Errorf("bob: %v", s)
+	}
+	if s := explain[cat].String(); s != "expected win probability 8%, you lost, rating -0.16, loss damped by low uncertainty" {
+		t.Errorf("cat: %v", s)
+	}
+}
@@ -0,0 +1,343 @@
+package trueskill
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"sort"
+	"time"
+)
+
+// The default limit on the number of sweeps of an FFACalc.
+const DefaultFFASweeps = 4
+
+// Stop sweeping once no precision changes by more than this.
+const ffaTolerance = 1e-6
+
+// Calculates new ratings for free-for-all matches between any number of
+// single-player teams, such as battle royales where only the elimination
+// order is known and many players tie.
+//
+// The TrueSkill factor graph of such a match is a chain: each player's
+// performance is compared only with the performances of the players placed
+// just above and just below, as a draw if they tied. Rather than building
+// the generic graph, the calculator passes messages along this chain
+// directly, in precision and precision-mean, sweeping down the finishing
+// order and back up until the messages settle or MaxSweeps is reached. A
+// match of n players thus costs O(n log n + n·MaxSweeps) however many tie.
+//
+// If only the first places of the match are known (see skills.Match.Known),
+// the chain stops at the last known place and every other player is
+// compared only with the player in that place, whom they lost to.
+// With two players the first sweep is exact; see TestFFAAccuracy for how
+// close a few sweeps come to full inference with more.
+//
+// No player has an advantage: GameInfo.Advantage and Match.Advantage are
+// ignored, both in rating and in the match quality.
+//
+// With a leaver policy (see skills.GameInfo.Leavers) the players who left
+// are rated in a second pass as having finished below everyone who did not,
+// while the others are rated on the result, with every other player their
+// opponent: the weight of their update is the policy's for opponents of the
+// first player to leave.
+type FFACalc struct {
+	// The most sweeps of message passing; 0 means DefaultFFASweeps.
+	MaxSweeps int
+}
+
+// Calculates new ratings based on the prior ratings and team ranks use 1 for first place, repeat the number for a tie (e.g. 1, 2, 2).
+func (calc *FFACalc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return calc.CalcMatch(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Calculates new ratings for the players of the match.
+func (calc *FFACalc) CalcMatch(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	newSkills, _ := calc.CalcUpdate(gi, m)
+	return newSkills
+}
+
+// Calculates new ratings for the players of the match like CalcMatch, along
+// with the priors they were updated from (see skills.UpdateCalc).
+func (calc *FFACalc) CalcUpdate(gi *skills.GameInfo, m *skills.Match) (skills.PlayerRatings, skills.PlayerRatings) {
+	validateTeamCount(m.Teams, ffaTeamRange)
+	validatePlayersPerTeam(m.Teams, ffaPlayerRange)
+	validateWeight(m.MatchWeight())
+
+	teams := anchoredTeams(gi.InactivePriors(m.Teams, m.Time))
+	weight := m.MatchWeight()
+	leftAt, left := 1.0, false
+	if gi.Leavers != nil {
+		for _, t := range teams {
+			if at, ok := t.LeftAt(); ok {
+				leftAt, left = math.Min(leftAt, at), true
+			}
+		}
+	}
+	if left {
+		weight *= gi.Leavers.Opponents + (1-gi.Leavers.Opponents)*leftAt
+	}
+
+	newSkills, priors := calc.rate(gi, teams, m.Ranks, m.Known, weight, m.Time)
+	if left {
+		lranks, known := leaverRanks(teams, m)
+		lost, lostPriors := calc.rate(gi, teams, lranks, known, m.MatchWeight(), m.Time)
+		for _, t := range teams {
+			if _, ok := t.LeftAt(); ok {
+				p := t.PlayerAt(0)
+				newSkills[p], priors[p] = lost[p], lostPriors[p]
+			}
+		}
+	}
+	keepAnchors(newSkills, m.Teams)
+	return newSkills, priors
+}
+
+// Returns the ranks of a match with every leaver placed below everyone who did
+// not leave, and the number of known places among them: the places of the
+// players known in the match who did not leave, or 0 if all are known.
+func leaverRanks(teams []skills.Team, m *skills.Match) ([]int, int) {
+	ranks := append([]int{}, m.Ranks...)
+	sorted := append([]int{}, m.Ranks...)
+	sort.Ints(sorted)
+	last := sorted[len(sorted)-1]
+
+	known := 0
+	for i, t := range teams {
+		if _, ok := t.LeftAt(); ok {
+			ranks[i] = last + 1
+		} else if m.Known > 0 && m.Known < len(teams) && m.Ranks[i] <= sorted[m.Known-1] {
+			known++
+		}
+	}
+	return ranks, known
+}
+
+// Rates single-player teams with the given ranks, of which the first known
+// places are known (all if known is 0), and returns the new ratings and the
+// priors they were updated from.
+func (calc *FFACalc) rate(gi *skills.GameInfo, teams []skills.Team, ranks []int, known int, weight float64, at time.Time) (skills.PlayerRatings, skills.PlayerRatings) {
+	// Copy slices so we don't confuse the client code
+	steams := append([]skills.Team{}, teams...)
+	sranks := append([]int{}, ranks...)
+
+	// Put the teams in finishing order, keeping the given order of ties so
+	// the chain is the same on every run
+	sort.Stable(skills.NewRankedTeams(steams, sranks))
+
+	n := len(steams)
+	players := make([]skills.Player, n)
+	priors := make([]skills.Rating, n)
+	for i, t := range steams {
+		players[i] = t.PlayerAt(0)
+		priors[i] = t.PlayerRating(players[i])
+	}
+
+	if known <= 0 || known > n {
+		known = n
+	}
+	if known < n && sranks[known] <= sranks[known-1] {
+		panic(fmt.Errorf("rank of unknown place [%v] not below last known place [%v]", sranks[known], sranks[known-1]))
+	}
+
+	chain := newFFAChain(gi, priors, sranks, known, weight)
+	sweeps := calc.MaxSweeps
+	if sweeps <= 0 {
+		sweeps = DefaultFFASweeps
+	}
+	for i := 0; i < sweeps && chain.sweep() > ffaTolerance; i++ {
+	}
+
+	newSkills := make(skills.PlayerRatings, n)
+	updatedFrom := make(skills.PlayerRatings, n)
+	for i, p := range players {
+		newSkills[p] = stamped(chain.posterior(i), priors[i], at)
+		updatedFrom[p] = withDynamics(gi, priors[i], weight)
+	}
+	return newSkills, updatedFrom
+}
+
+// The messages of the comparisons of a free-for-all match on the players'
+// performances. Players are in finishing order.
+type ffaChain struct {
+	gi         *skills.GameInfo
+	priors     []skills.Rating
+	ranks      []int
+	drawMargin float64
+	tauSqr     float64
+	weight     float64
+
+	// The players compared, the upper one first: consecutive players down
+	// to the last known place, then that player and each of the others
+	upper, lower []int
+
+	// The performance priors and marginals
+	priorPi, priorTau []float64
+	pi, tau           []float64
+
+	// The messages each comparison sends to its upper and lower player
+	upPi, upTau     []float64
+	downPi, downTau []float64
+}
+
+func newFFAChain(gi *skills.GameInfo, priors []skills.Rating, ranks []int, known int, weight float64) *ffaChain {
+	c := &ffaChain{}
+	c.reset(gi, priors, ranks, known, weight)
+	return c
+}
+
+// Sets the chain up for a new match, reusing its slices where they are large
+// enough.
+func (c *ffaChain) reset(gi *skills.GameInfo, priors []skills.Rating, ranks []int, known int, weight float64) {
+	n := len(priors)
+	c.gi, c.priors, c.ranks = gi, priors, ranks
+	c.drawMargin = gameDrawMargin(gi)
+	c.tauSqr = dynamicsVariance(gi, weight)
+	c.weight = weight
+	c.upper, c.lower = resizeInts(c.upper, n-1), resizeInts(c.lower, n-1)
+	c.priorPi, c.priorTau = resizeFloats(c.priorPi, n), resizeFloats(c.priorTau, n)
+	c.pi, c.tau = resizeFloats(c.pi, n), resizeFloats(c.tau, n)
+	c.upPi, c.upTau = resizeFloats(c.upPi, n-1), resizeFloats(c.upTau, n-1)
+	c.downPi, c.downTau = resizeFloats(c.downPi, n-1), resizeFloats(c.downTau, n-1)
+	for k := range c.upPi {
+		c.upPi[k], c.upTau[k], c.downPi[k], c.downTau[k] = 0, 0, 0, 0
+	}
+
+	for k := range c.upper {
+		c.upper[k], c.lower[k] = k, k+1
+		if k >= known {
+			c.upper[k] = known - 1
+		}
+	}
+	for i, r := range priors {
+		c.priorPi[i] = 1 / (r.Variance() + c.tauSqr + gi.PerformanceVariance(r))
+		c.priorTau[i] = r.Mean() * c.priorPi[i]
+	}
+	copy(c.pi, c.priorPi)
+	copy(c.tau, c.priorTau)
+}
+
+// Updates the messages of every comparison down the finishing order and back
+// up, and returns the largest change in the precision of a marginal.
+func (c *ffaChain) sweep() (delta float64) {
+	for k := range c.upper {
+		delta = math.Max(delta, c.update(k))
+	}
+	for k := len(c.upper) - 2; k >= 0; k-- {
+		delta = math.Max(delta, c.update(k))
+	}
+	return
+}
+
+// Updates the messages of the k-th comparison and returns the larger change
+// in the precisions of its two marginals.
+func (c *ffaChain) update(k int) float64 {
+	u, l := c.upper[k], c.lower[k]
+
+	// The performances without this comparison's messages
+	upperPi, upperTau := c.pi[u]-c.upPi[k], c.tau[u]-c.upTau[k]
+	lowerPi, lowerTau := c.pi[l]-c.downPi[k], c.tau[l]-c.downTau[k]
+	upperMean, upperVar := upperTau/upperPi, 1/upperPi
+	lowerMean, lowerVar := lowerTau/lowerPi, 1/lowerPi
+
+	cc := math.Sqrt(upperVar + lowerVar)
+	v, w := corrections(c.gi, upperMean-lowerMean, c.drawMargin, cc, c.ranks[u] == c.ranks[l])
+
+	newUpperPi := 1 / (upperVar * (1 - w*upperVar/numerics.Sqr(cc)))
+	newUpperTau := (upperMean + upperVar/cc*v) * newUpperPi
+	newLowerPi := 1 / (lowerVar * (1 - w*lowerVar/numerics.Sqr(cc)))
+	newLowerTau := (lowerMean - lowerVar/cc*v) * newLowerPi
+
+	delta := math.Max(math.Abs(newUpperPi-c.pi[u]), math.Abs(newLowerPi-c.pi[l]))
+
+	c.upPi[k], c.upTau[k] = newUpperPi-upperPi, newUpperTau-upperTau
+	c.downPi[k], c.downTau[k] = newLowerPi-lowerPi, newLowerTau-lowerTau
+	c.pi[u], c.tau[u] = newUpperPi, newUpperTau
+	c.pi[l], c.tau[l] = newLowerPi, newLowerTau
+	return delta
+}
+
+// Returns the posterior skill of player i: the prior times the messages to
+// their performance, widened by their performance variance and tempered by
+// the weight of the match.
+func (c *ffaChain) posterior(i int) skills.Rating {
+	r := c.priors[i]
+	priorVar := r.Variance() + c.tauSqr
+
+	msgPi, msgTau := c.pi[i]-c.priorPi[i], c.tau[i]-c.priorTau[i]
+
+	// The messages widened by the performance variance, in a form that holds
+	// for messages of zero precision, which shift the mean without
+	// narrowing it. The corrections keep their precision non-negative.
+	widen := 1 + msgPi*c.gi.PerformanceVariance(r)
+	precision := 1/priorVar + c.weight*msgPi/widen
+	precisionMean := r.Mean()/priorVar + c.weight*msgTau/widen
+	return skills.NewRating(precisionMean/precision, math.Sqrt(1/precision))
+}
+
+// Returns the posterior skill mean and variance of a player with the given
+// prior on the team in place k, whose performance is the sum of its players'
+// and has the prior of place k. The player's skill moves with the team's
+// performance in proportion to their share of its variance, as in
+// TwoTeamCalc. Only for matches of weight 1.
+func (c *ffaChain) teamPosterior(k int, mean, variance float64) (float64, float64) {
+	perfVar, perfMean := 1/c.priorPi[k], c.priorTau[k]/c.priorPi[k]
+	postVar, postMean := 1/c.pi[k], c.tau[k]/c.pi[k]
+	share := (variance + c.tauSqr) / perfVar
+	return mean + share*(postMean-perfMean), variance + c.tauSqr - share*share*(perfVar-postVar)
+}
+
+// Calculates the match quality as the likelihood of all teams drawing (0% = bad, 100% = well matched).
+// This is equation 4.1 of the TrueSkill paper for n players, with A the
+// n×(n-1) matrix of differences between consecutive players, B the diagonal
+// matrix of performance variances and Σ that of skill variances:
+//
+//	sqrt(det(AᵀBA) / det(AᵀBA + AᵀΣA)) · exp(-½ μᵀA (AᵀBA + AᵀΣA)⁻¹ Aᵀμ)
+//
+// It takes O(n³) time, so it is meant for match making rather than for
+// rating 100 player matches.
+func (calc *FFACalc) CalcMatchQual(gi *skills.GameInfo, teams []skills.Team) float64 {
+	validateTeamCount(teams, ffaTeamRange)
+	validatePlayersPerTeam(teams, ffaPlayerRange)
+	teams = anchoredTeams(teams)
+
+	n := len(teams)
+	means := make([]float64, n)
+	perfVars := make([]float64, n)
+	skillVars := make([]float64, n)
+	for i, t := range teams {
+		r := t.PlayerRating(t.PlayerAt(0))
+		means[i] = r.Mean()
+		perfVars[i] = gi.PerformanceVariance(r)
+		skillVars[i] = r.Variance()
+	}
+
+	a := numerics.NewMatrix(n, n-1)
+	for j := 0; j < n-1; j++ {
+		a.SetAt(j, j, 1)
+		a.SetAt(j+1, j, -1)
+	}
+	at := new(numerics.Matrix).Transpose(a)
+
+	// AᵀXA for a diagonal X
+	sandwich := func(diag []float64) *numerics.Matrix {
+		xa := new(numerics.Matrix).Mul(numerics.NewDiagonalMatrix(diag...), a)
+		return xa.Mul(at, xa)
+	}
+
+	perf := sandwich(perfVars)
+	total := new(numerics.Matrix).Add(perf, sandwich(skillVars))
+
+	atMean := new(numerics.Matrix).Mul(at, numerics.NewVector(means...))
+	exponent := new(numerics.Matrix).Mul(new(numerics.Matrix).Transpose(atMean), new(numerics.Matrix).Mul(new(numerics.Matrix).Inverse(total), atMean))
+
+	sqrtPart := math.Sqrt(perf.Determinant() / total.Determinant())
+	expPart := math.Exp(-.5 * exponent.At(0, 0))
+
+	return sqrtPart * expPart
+}
+
+var (
+	ffaTeamRange   = numerics.AtLeast(2)
+	ffaPlayerRange = numerics.Exactly(1)
+)
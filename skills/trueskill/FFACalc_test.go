@@ -0,0 +1,291 @@
+package trueskill
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFFACalc(t *testing.T) {
+	AllTwoPlayerScenarios(t, &FFACalc{})
+}
+
+func ffaTeams(priors ...skills.Rating) []skills.Team {
+	teams := make([]skills.Team, len(priors))
+	for i, r := range priors {
+		teams[i] = skills.NewTeam()
+		teams[i].AddPlayer(*skills.NewPlayer(i + 1), r)
+	}
+	return teams
+}
+
+func TestFFAMatchQuality(t *testing.T) {
+	calc := &FFACalc{}
+	gameInfo := skills.DefaultGameInfo
+	r := gameInfo.DefaultRating()
+
+	// From the C# TrueSkillCalculatorTests
+	AssertMatchQuality(t, 0.200, calc.CalcMatchQual(gameInfo, ffaTeams(r, r, r)))
+	AssertMatchQuality(t, 0.089, calc.CalcMatchQual(gameInfo, ffaTeams(r, r, r, r)))
+	AssertMatchQuality(t, 0.040, calc.CalcMatchQual(gameInfo, ffaTeams(r, r, r, r, r)))
+	AssertMatchQuality(t, 0.004, calc.CalcMatchQual(gameInfo, ffaTeams(r, r, r, r, r, r, r, r)))
+
+	// The same as the two player calculator's for two players
+	teams := ffaTeams(r, skills.NewRating(30, 4))
+	AssertMatchQuality(t, (&TwoPlayerCalc{}).CalcMatchQual(gameInfo, teams), calc.CalcMatchQual(gameInfo, teams))
+}
+
+// Rates a match with full inference: message passing run to convergence.
+func ffaFull(gi *skills.GameInfo, teams []skills.Team, ranks []int) skills.PlayerRatings {
+	return (&FFACalc{MaxSweeps: 1000}).CalcNewRatings(gi, teams, ranks...)
+}
+
+// Returns the largest differences of the means and standard deviations of
+// a calculator from full inference.
+func ffaErrors(calc *FFACalc, gi *skills.GameInfo, teams []skills.Team, ranks []int) (meanErr, stddevErr float64) {
+	got := calc.CalcNewRatings(gi, teams, ranks...)
+	want := ffaFull(gi, teams, ranks)
+	for p, r := range got {
+		meanErr = math.Max(meanErr, math.Abs(r.Mean()-want[p].Mean()))
+		stddevErr = math.Max(stddevErr, math.Abs(r.Stddev()-want[p].Stddev()))
+	}
+	return
+}
+
+func assertFFARatings(t *testing.T, want []float64, teams []skills.Team, got skills.PlayerRatings) {
+	for i, team := range teams {
+		AssertRating(t, want[2*i], want[2*i+1], got[team.PlayerAt(0)])
+	}
+}
+
+func TestFFAAccuracy(t *testing.T) {
+	gameInfo := skills.DefaultGameInfo
+	r := gameInfo.DefaultRating()
+
+	// Full inference agrees with the C# factor graph calculator
+	teams := ffaTeams(r, r, r)
+	assertFFARatings(t, []float64{31.675, 6.656, 25.000, 6.208, 18.325, 6.656}, teams, ffaFull(gameInfo, teams, []int{1, 2, 3}))
+	assertFFARatings(t, []float64{25.000, 5.698, 25.000, 5.695, 25.000, 5.698}, teams, ffaFull(gameInfo, teams, []int{1, 1, 1}))
+
+	teams = ffaTeams(r, r, r, r, r)
+	assertFFARatings(t, []float64{34.363, 6.136, 29.058, 5.536, 25.000, 5.420, 20.942, 5.536, 15.637, 6.136}, teams, ffaFull(gameInfo, teams, []int{1, 2, 3, 4, 5}))
+
+	teams = ffaTeams(
+		skills.NewRating(10, 8), skills.NewRating(15, 7), skills.NewRating(20, 6), skills.NewRating(25, 5),
+		skills.NewRating(30, 4), skills.NewRating(35, 3), skills.NewRating(40, 2), skills.NewRating(45, 1))
+	assertFFARatings(t, []float64{35.135, 4.506, 32.585, 4.037, 31.329, 3.756, 30.984, 3.453, 31.751, 3.064, 34.051, 2.541, 38.263, 1.849, 44.118, 0.983},
+		teams, ffaFull(gameInfo, teams, []int{1, 2, 3, 4, 5, 6, 7, 8}))
+
+	// Random matches of up to 8 players with ties, rated with a limited
+	// number of sweeps
+	rnd := rand.New(rand.NewSource(1))
+	const trials = 500
+	type match struct {
+		teams []skills.Team
+		ranks []int
+	}
+	matches := make([]match, trials)
+	for k := range matches {
+		n := 2 + rnd.Intn(7)
+		priors := make([]skills.Rating, n)
+		ranks := make([]int, n)
+		for i := range priors {
+			priors[i] = skills.NewRating(15+20*rnd.Float64(), 1+7*rnd.Float64())
+			ranks[i] = i + 1
+			if i > 0 && rnd.Intn(4) == 0 {
+				ranks[i] = ranks[i-1]
+			}
+		}
+		matches[k] = match{ffaTeams(priors...), ranks}
+	}
+
+	for _, sweeps := range []int{1, 2, DefaultFFASweeps} {
+		calc := &FFACalc{MaxSweeps: sweeps}
+		var maxMeanErr, maxStddevErr, sumMeanErr float64
+		for _, m := range matches {
+			meanErr, stddevErr := ffaErrors(calc, gameInfo, m.teams, m.ranks)
+			if len(m.teams) == 2 && (meanErr > 1e-9 || stddevErr > 1e-9) {
+				t.Errorf("%v sweeps: two player errors = %v, %v, want exact results", sweeps, meanErr, stddevErr)
+			}
+			maxMeanErr = math.Max(maxMeanErr, meanErr)
+			maxStddevErr = math.Max(maxStddevErr, stddevErr)
+			sumMeanErr += meanErr / trials
+		}
+		t.Logf("%v sweeps: mean error %.4f on average, %.4f at most; stddev error %.4f at most", sweeps, sumMeanErr, maxMeanErr, maxStddevErr)
+		if sweeps == DefaultFFASweeps && (maxMeanErr > 0.01 || maxStddevErr > 0.01) {
+			t.Errorf("%v sweeps: errors = %v, %v, want at most 0.01", sweeps, maxMeanErr, maxStddevErr)
+		}
+	}
+
+	// A battle royale
+	teams, ranks := battleRoyale()
+	meanErr, stddevErr := ffaErrors(&FFACalc{}, gameInfo, teams, ranks)
+	t.Logf("%v players: mean error %.4f, stddev error %.4f at most", len(teams), meanErr, stddevErr)
+	if meanErr > 0.05 || stddevErr > 0.05 {
+		t.Errorf("%v players: errors = %v, %v, want at most 0.05", len(teams), meanErr, stddevErr)
+	}
+}
+
+// Returns 100 players eliminated in 40 waves.
+func battleRoyale() ([]skills.Team, []int) {
+	rnd := rand.New(rand.NewSource(1))
+	const n = 100
+	priors := make([]skills.Rating, n)
+	ranks := make([]int, n)
+	for i := range priors {
+		priors[i] = skills.NewRating(15+20*rnd.Float64(), 1+7*rnd.Float64())
+		ranks[i] = 1 + rnd.Intn(40)
+	}
+	return ffaTeams(priors...), ranks
+}
+
+func BenchmarkFFACalc(b *testing.B) {
+	gameInfo := skills.DefaultGameInfo
+	teams, ranks := battleRoyale()
+	calc := &FFACalc{}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		calc.CalcNewRatings(gameInfo, teams, ranks...)
+	}
+}
+
+// Estimates the posterior skills of a match where only the first known
+// places are known by sampling skills and performances from the priors and
+// keeping the samples that agree with the result.
+func sampleFFAPosteriors(gi *skills.GameInfo, priors []skills.Rating, known int) []skills.Rating {
+	rnd := rand.New(rand.NewSource(1))
+	n := len(priors)
+	drawMargin := gameDrawMargin(gi)
+	tauSqr := numerics.Sqr(gi.DynamicsFactor)
+
+	sum, sumSqr := make([]float64, n), make([]float64, n)
+	kept := 0
+	s, p := make([]float64, n), make([]float64, n)
+	for k := 0; k < 2000000; k++ {
+		for i, r := range priors {
+			s[i] = r.Mean() + math.Sqrt(r.Variance()+tauSqr)*rnd.NormFloat64()
+			p[i] = s[i] + gi.Beta*rnd.NormFloat64()
+		}
+		ok := true
+		for i := 1; i < n && ok; i++ {
+			// Each known place beat the next; the others lost to the last
+			j := i - 1
+			if i > known {
+				j = known - 1
+			}
+			ok = p[j]-p[i] > drawMargin
+		}
+		if !ok {
+			continue
+		}
+		kept++
+		for i := range s {
+			sum[i] += s[i]
+			sumSqr[i] += s[i] * s[i]
+		}
+	}
+
+	posteriors := make([]skills.Rating, n)
+	for i := range posteriors {
+		mean := sum[i] / float64(kept)
+		posteriors[i] = skills.NewRating(mean, math.Sqrt(sumSqr[i]/float64(kept)-mean*mean))
+	}
+	return posteriors
+}
+
+func TestFFAOutliers(t *testing.T) {
+	gameInfo := *skills.DefaultGameInfo
+	teams := ffaTeams(skills.NewRating(10, 3), skills.NewRating(25, 8.3), skills.NewRating(40, 1), skills.NewRating(40, 1))
+	ranks := []int{1, 2, 3, 3}
+	plain := (&FFACalc{}).CalcNewRatings(&gameInfo, teams, ranks...)
+
+	// Outliers damp the massive upset, under either curve, while keeping
+	// every rating proper
+	gameInfo.OutlierProbability = 0.05
+	for _, curve := range []numerics.Curve{numerics.GaussCurve{}, numerics.LogisticCurve{}} {
+		gameInfo.Curve = curve
+		got := (&FFACalc{}).CalcNewRatings(&gameInfo, teams, ranks...)
+		for i, team := range teams {
+			if r := got[team.PlayerAt(0)]; math.IsNaN(r.Mean()) || !(r.Stddev() > 0) || r.Stddev() > team.PlayerRating(team.PlayerAt(0)).Stddev()+1 {
+				t.Errorf("%T: player %v = %v", curve, i+1, r)
+			}
+		}
+		first := teams[0].PlayerAt(0)
+		if gained := got[first].Mean() - 10; gained <= 0 || gained >= plain[first].Mean()-10 {
+			t.Errorf("%T: upset winner gained %v with outliers, %v without", curve, gained, plain[first].Mean()-10)
+		}
+	}
+}
+
+func TestFFAPartialRanking(t *testing.T) {
+	gameInfo := skills.DefaultGameInfo
+	priors := []skills.Rating{
+		skills.NewRating(25, 6), skills.NewRating(28, 4), skills.NewRating(20, 7), skills.NewRating(25, 8.3), skills.NewRating(30, 3),
+	}
+	teams := ffaTeams(priors...)
+
+	for _, m := range []*skills.Match{
+		skills.NewWinnerMatch(teams, 0),
+		skills.NewPartialMatch(teams, 2, 1, 2, 3, 3, 3),
+		skills.NewPartialMatch(teams, 5, 1, 2, 3, 4, 5),
+	} {
+		got := (&FFACalc{}).CalcMatch(gameInfo, m)
+		want := sampleFFAPosteriors(gameInfo, priors, m.Known)
+		for i, team := range teams {
+			r := got[team.PlayerAt(0)]
+			if math.Abs(r.Mean()-want[i].Mean()) > 0.25 || math.Abs(r.Stddev()-want[i].Stddev()) > 0.25 {
+				t.Errorf("%v known: player %v = %v, sampled %v", m.Known, i+1, r, want[i])
+			}
+		}
+	}
+
+	// Unknown places must be below the known ones
+	defer func() {
+		if recover() == nil {
+			t.Errorf("unknown place ranked with the known ones did not panic")
+		}
+	}()
+	(&FFACalc{}).CalcMatch(gameInfo, skills.NewPartialMatch(teams, 2, 1, 2, 2, 3, 3))
+}
+
+func TestFFALeavers(t *testing.T) {
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.Leavers = &skills.LeaverPolicy{Opponents: 0.5}
+	prior := skills.NewRating(25, 6)
+
+	// The second player has the given status, leaving half way if they left
+	teams := func(status skills.Status) []skills.Team {
+		teams := ffaTeams(prior, prior, prior, prior)
+		teams[1].AddPlayerAttrs(*skills.NewPlayer(2), prior, skills.PlayerAttrs{Weight: 1, Status: status, LeftAt: 0.5})
+		return teams
+	}
+	plain := (&FFACalc{}).CalcNewRatings(skills.DefaultGameInfo, teams(skills.Completed), 1, 2, 3, 4)
+
+	// Without leavers the policy changes nothing
+	for p, r := range (&FFACalc{}).CalcNewRatings(&gameInfo, teams(skills.Completed), 1, 2, 3, 4) {
+		AssertRating(t, plain[p].Mean(), plain[p].Stddev(), r)
+	}
+
+	// The AFK player finishes last, and the others' results count for half
+	afk := (&FFACalc{}).CalcNewRatings(&gameInfo, teams(skills.AFK), 1, 2, 3, 4)
+	last := (&FFACalc{}).CalcNewRatings(skills.DefaultGameInfo, teams(skills.Completed), 1, 5, 3, 4)
+	p2 := *skills.NewPlayer(2)
+	AssertRating(t, last[p2].Mean(), last[p2].Stddev(), afk[p2])
+	if r := afk[*skills.NewPlayer(1)]; !(25 < r.Mean() && r.Mean() < plain[*skills.NewPlayer(1)].Mean()) {
+		t.Errorf("winner after a match with a leaver = %v, plain win = %v", r, plain[*skills.NewPlayer(1)])
+	}
+
+	// Leaving late spares the others less
+	late := (&FFACalc{}).CalcNewRatings(&gameInfo, teams(skills.Left), 1, 2, 3, 4)
+	if a, b := late[*skills.NewPlayer(1)].Mean(), afk[*skills.NewPlayer(1)].Mean(); !(b < a && a < plain[*skills.NewPlayer(1)].Mean()) {
+		t.Errorf("winner's mean after a late leaver = %v, want between %v and %v", a, b, plain[*skills.NewPlayer(1)].Mean())
+	}
+
+	// With only the winner known the leaver loses to them like the rest
+	got := (&FFACalc{}).CalcMatch(&gameInfo, skills.NewWinnerMatch(teams(skills.AFK), 0))
+	want := (&FFACalc{}).CalcMatch(skills.DefaultGameInfo, skills.NewWinnerMatch(teams(skills.Completed), 0))
+	AssertRating(t, want[p2].Mean(), want[p2].Stddev(), got[p2])
+}
@@ -0,0 +1,175 @@
+package trueskill
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"io"
+	"math"
+	"sort"
+)
+
+// The names of the layers of a TrueSkill factor graph, in the order they are built.
+const (
+	PriorLayer           = "PlayerPriorValuesToSkills"
+	PerformanceLayer     = "PlayerSkillsToPerformances"
+	TeamPerformanceLayer = "PlayerPerformancesToTeamPerformances"
+	DifferenceLayer      = "TeamPerformancesToTeamPerformanceDifferences"
+	ComparisonLayer      = "TeamDifferencesComparison"
+)
+
+// A variable of a factor graph along with its current marginal.
+type GraphVar struct {
+	Name     string
+	Marginal *numerics.GaussDist
+}
+
+// A factor of a factor graph and the variables it is connected to.
+type GraphFactor struct {
+	Type  string
+	Layer string
+	Vars  []*GraphVar
+}
+
+// A TrueSkill factor graph for a single match. Variables and factors are
+// kept in the order they are built, layer by layer.
+type FactorGraph struct {
+	Vars    []*GraphVar
+	Factors []*GraphFactor
+}
+
+// Returns the factor graph of a match between two teams, for visualising
+// the closed-form result of TwoTeamCalc: no messages are passed. Every
+// variable is a linear function of the players' skills, so its marginal is
+// computed directly from its covariance with the team performance
+// difference, which is what message passing would converge to on this
+// tree-shaped graph. The marginals agree with the ratings computed by
+// TwoTeamCalc for the plain match the graph models: the game's fixed
+// advantage and the players' own betas are taken into account, but not
+// match weights, learned advantages, anchored players, leavers or growth
+// with inactivity.
+func TwoTeamGraph(gi *skills.GameInfo, teams []skills.Team, ranks ...int) *FactorGraph {
+	validateTeamCount(teams, twoTeamTeamRange)
+	validatePlayersPerTeam(teams, twoTeamPlayerRange)
+
+	// Copy slices so we don't confuse the client code
+	steams := append([]skills.Team{}, teams...)
+	sranks := append([]int{}, ranks...)
+
+	// Make sure things are in order
+	sort.Sort(skills.NewRankedTeams(steams, sranks))
+
+	drawMargin := gameDrawMargin(gi)
+	tauSqr := numerics.Sqr(gi.DynamicsFactor)
+
+	// The advantage of the first of the sorted teams over the second
+	adv := sortedAdvantage(skills.NewRating(gi.Advantage, 0), ranks)
+
+	meanDelta := steams[0].Accum(skills.MeanSum) + adv.Mean() - steams[1].Accum(skills.MeanSum)
+	c := math.Sqrt(steams[0].Accum(skills.VarianceSum) + steams[1].Accum(skills.VarianceSum) + steams[0].Accum(gi.PerformanceVarianceSum) + steams[1].Accum(gi.PerformanceVarianceSum))
+
+	wasDraw := sranks[0] == sranks[1]
+
+	v, w := corrections(gi, meanDelta, drawMargin, c, wasDraw)
+	comparison := "GaussianGreaterThanFactor"
+	if wasDraw {
+		comparison = "GaussianWithinFactor"
+	}
+
+	// Every variable is a linear function of the players' skills, so its
+	// marginal follows from its covariance with the team difference.
+	marginal := func(mean, variance, cov float64) *numerics.GaussDist {
+		return numerics.NewGaussDist(mean+cov*v/c, math.Sqrt(variance-numerics.Sqr(cov)*w/numerics.Sqr(c)))
+	}
+
+	g := &FactorGraph{}
+
+	teamPerfs := make([]*GraphVar, len(steams))
+	for i, team := range steams {
+		sign := 1.0
+		if i == 1 {
+			sign = -1
+		}
+
+		var perfs []*GraphVar
+		var teamMean, teamVar float64
+		if i == 0 {
+			teamMean = adv.Mean()
+		}
+		for _, p := range team.Players() {
+			r := team.PlayerRating(p)
+			skillVar := r.Variance() + tauSqr
+			perfVar := skillVar + gi.PerformanceVariance(r)
+
+			skill := g.addVar(fmt.Sprintf("skill %v", p), marginal(r.Mean(), skillVar, sign*skillVar))
+			g.addFactor("GaussianPriorFactor", PriorLayer, skill)
+
+			perf := g.addVar(fmt.Sprintf("performance %v", p), marginal(r.Mean(), perfVar, sign*perfVar))
+			g.addFactor("GaussianLikelihoodFactor", PerformanceLayer, skill, perf)
+
+			perfs = append(perfs, perf)
+			teamMean += r.Mean()
+			teamVar += perfVar
+		}
+
+		teamPerfs[i] = g.addVar(fmt.Sprintf("team %v performance", i+1), marginal(teamMean, teamVar, sign*teamVar))
+		g.addFactor("GaussianWeightedSumFactor", TeamPerformanceLayer, append([]*GraphVar{teamPerfs[i]}, perfs...)...)
+	}
+
+	diff := g.addVar("team 1 - team 2", numerics.NewGaussDist(meanDelta+c*v, c*math.Sqrt(1-w)))
+	g.addFactor("GaussianWeightedSumFactor", DifferenceLayer, diff, teamPerfs[0], teamPerfs[1])
+	g.addFactor(comparison, ComparisonLayer, diff)
+
+	return g
+}
+
+func (g *FactorGraph) addVar(name string, marginal *numerics.GaussDist) *GraphVar {
+	v := &GraphVar{name, marginal}
+	g.Vars = append(g.Vars, v)
+	return v
+}
+
+func (g *FactorGraph) addFactor(typ, layer string, vars ...*GraphVar) {
+	g.Factors = append(g.Factors, &GraphFactor{typ, layer, vars})
+}
+
+// WriteDot writes the graph in the Graphviz DOT language. Each layer is drawn
+// as a cluster, variables are labeled with their marginals and factors with
+// their types.
+func (g *FactorGraph) WriteDot(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	varIds := make(map[*GraphVar]string, len(g.Vars))
+	fmt.Fprintln(b, "graph trueskill {")
+	for i, v := range g.Vars {
+		varIds[v] = fmt.Sprintf("v%d", i)
+		fmt.Fprintf(b, "\tv%d [shape=ellipse, label=%q];\n", i, fmt.Sprintf("%v\nμ=%.4f σ=%.4f", v.Name, v.Marginal.Mean, v.Marginal.Stddev))
+	}
+
+	var layers []string
+	layerFactors := make(map[string][]int)
+	for i, f := range g.Factors {
+		if _, ok := layerFactors[f.Layer]; !ok {
+			layers = append(layers, f.Layer)
+		}
+		layerFactors[f.Layer] = append(layerFactors[f.Layer], i)
+	}
+
+	for _, layer := range layers {
+		fmt.Fprintf(b, "\tsubgraph \"cluster_%v\" {\n\t\tlabel=%q;\n", layer, layer)
+		for _, i := range layerFactors[layer] {
+			fmt.Fprintf(b, "\t\tf%d [shape=box, label=%q];\n", i, g.Factors[i].Type)
+		}
+		fmt.Fprintln(b, "\t}")
+	}
+
+	for i, f := range g.Factors {
+		for _, v := range f.Vars {
+			fmt.Fprintf(b, "\tf%d -- %v;\n", i, varIds[v])
+		}
+	}
+	fmt.Fprintln(b, "}")
+
+	return b.Flush()
+}
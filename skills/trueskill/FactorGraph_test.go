@@ -0,0 +1,110 @@
+package trueskill
+
+import (
+	"bytes"
+	"github.com/ChrisHines/GoSkills/skills"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTwoTeamGraphMarginals(t *testing.T) {
+	player1 := skills.NewPlayer(1)
+	player2 := skills.NewPlayer(2)
+	player3 := skills.NewPlayer(3)
+	gameInfo := skills.DefaultGameInfo
+
+	team1 := skills.NewTeam()
+	team1.AddPlayer(*player1, skills.NewRating(20, 8))
+	team1.AddPlayer(*player2, skills.NewRating(25, 6))
+
+	team2 := skills.NewTeam()
+	team2.AddPlayer(*player3, skills.NewRating(35, 7))
+
+	for _, ranks := range [][]int{{1, 2}, {2, 1}, {1, 1}} {
+		teams := []skills.Team{team1, team2}
+		newRatings := (&TwoTeamCalc{}).CalcNewRatings(gameInfo, teams, ranks...)
+		g := TwoTeamGraph(gameInfo, teams, ranks...)
+
+		for _, p := range []*skills.Player{player1, player2, player3} {
+			want := newRatings[*p]
+			found := false
+			for _, v := range g.Vars {
+				if v.Name == "skill "+p.String() {
+					found = true
+					AssertRating(t, want.Mean(), want.Stddev(), skills.NewRating(v.Marginal.Mean, v.Marginal.Stddev))
+				}
+			}
+			if !found {
+				t.Errorf("no skill variable for player %v", p)
+			}
+		}
+	}
+}
+
+func TestTwoTeamGraphPinned(t *testing.T) {
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.Advantage = 3
+
+	team1 := skills.NewTeam()
+	team1.AddPlayer(*skills.NewPlayer(1), skills.NewRating(20, 8).WithBeta(2))
+	team1.AddPlayer(*skills.NewPlayer(2), skills.NewRating(25, 6))
+	team2 := skills.NewTeam()
+	team2.AddPlayer(*skills.NewPlayer(3), skills.NewRating(35, 7))
+	teams := []skills.Team{team1, team2}
+
+	for _, ranks := range [][]int{{1, 2}, {2, 1}, {1, 1}} {
+		newRatings := (&TwoTeamCalc{}).CalcNewRatings(&gameInfo, teams, ranks...)
+		marginals := make(map[string]skills.Rating)
+		for _, v := range TwoTeamGraph(&gameInfo, teams, ranks...).Vars {
+			marginals[v.Name] = skills.NewRating(v.Marginal.Mean, v.Marginal.Stddev)
+		}
+		for p, want := range newRatings {
+			got := marginals["skill "+p.String()]
+			if math.Abs(got.Mean()-want.Mean()) > 1e-12 || math.Abs(got.Stddev()-want.Stddev()) > 1e-12 {
+				t.Errorf("ranks %v: skill %v = %v, want %v", ranks, p, got, want)
+			}
+		}
+	}
+}
+
+func TestTwoTeamGraphWriteDot(t *testing.T) {
+	player1 := skills.NewPlayer(1)
+	player2 := skills.NewPlayer(2)
+	gameInfo := skills.DefaultGameInfo
+
+	team1 := skills.NewTeam()
+	team1.AddPlayer(*player1, gameInfo.DefaultRating())
+
+	team2 := skills.NewTeam()
+	team2.AddPlayer(*player2, gameInfo.DefaultRating())
+
+	g := TwoTeamGraph(gameInfo, []skills.Team{team1, team2}, 1, 1)
+	if n := len(g.Vars); n != 7 {
+		t.Errorf("len(g.Vars) = %v, want %v", n, 7)
+	}
+	if n := len(g.Factors); n != 8 {
+		t.Errorf("len(g.Factors) = %v, want %v", n, 8)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteDot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+
+	for _, want := range []string{
+		"graph trueskill {",
+		"cluster_" + PriorLayer,
+		"cluster_" + ComparisonLayer,
+		"GaussianWithinFactor",
+		"μ=25.0000 σ=6.457",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot output does not contain %q\n%v", want, dot)
+		}
+	}
+	if n := strings.Count(dot, "subgraph"); n != 5 {
+		t.Errorf("dot output has %v layers, want %v", n, 5)
+	}
+}
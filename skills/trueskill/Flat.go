@@ -0,0 +1,189 @@
+package trueskill
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"sort"
+)
+
+// The flat versions of the calculators work on flat slices without
+// allocating, for tight loops and simulations: TwoTeamFlat for matches of two
+// teams (including two players, as teams of one) and FFAFlat for free-for-all
+// matches.
+
+// Calculates new ratings for a match between two teams like TwoTeamCalc.
+//
+// Player i has skill mean means[i] and variance vars[i]. The players of team
+// t run from teamStart[t] to the start of the next team or the end of the
+// slices, so teamStart is {0, size of the first team}. ranks holds the rank of
+// each team, as for CalcNewRatings. The posterior means and variances are
+// written to newMeans and newVars, which may be means and vars themselves.
+//
+// Every player uses the game's beta, the first team gets the game's fixed
+// advantage and the match has a weight of 1; inactivity, anchors and leavers
+// are for CalcMatch.
+func TwoTeamFlat(gi *skills.GameInfo, means, vars []float64, teamStart, ranks []int, newMeans, newVars []float64) {
+	n := len(means)
+	if len(vars) != n || len(newMeans) != n || len(newVars) != n {
+		panic(fmt.Errorf("slices of %v means, %v variances, %v new means and %v new variances", n, len(vars), len(newMeans), len(newVars)))
+	}
+	if len(teamStart) != 2 || len(ranks) != 2 {
+		panic(fmt.Errorf("%v team starts and %v ranks for two teams", len(teamStart), len(ranks)))
+	}
+	if teamStart[0] != 0 || teamStart[1] < 1 || teamStart[1] >= n {
+		panic(fmt.Errorf("team starts %v do not split %v players into two teams", teamStart, n))
+	}
+
+	drawMargin := gameDrawMargin(gi)
+	tauSqr := numerics.Sqr(gi.DynamicsFactor)
+	split := teamStart[1]
+
+	// Sum over the teams and over all players
+	var meanSums [2]float64
+	varSum := float64(n) * numerics.Sqr(gi.Beta)
+	for i := range means {
+		meanSums[cond(i < split, 0, 1)] += means[i]
+		varSum += vars[i]
+	}
+	meanSums[0] += gi.Advantage
+	c := math.Sqrt(varSum)
+
+	// The corrections are those of the first team in finishing order; the
+	// other team's are the same with the mean update negated
+	first := cond(ranks[1] < ranks[0], 1, 0)
+	meanDelta := meanSums[first] - meanSums[1-first]
+	v, w := corrections(gi, meanDelta, drawMargin, c, ranks[0] == ranks[1])
+
+	for i := range means {
+		sign := 1.0
+		if cond(i < split, 0, 1) != first {
+			sign = -1
+		}
+		varWithDynamics := vars[i] + tauSqr
+		newMeans[i] = means[i] + sign*varWithDynamics/c*v
+		newVars[i] = varWithDynamics * (1 - w*varWithDynamics/numerics.Sqr(c))
+	}
+}
+
+// Reusable buffers of FFAFlat. Once they have held a match, matches of as many
+// players or fewer are rated without allocating. The zero value is ready to
+// use; buffers must not be shared between goroutines.
+type FFABuffers struct {
+	chain   ffaChain
+	order   []int // The players in finishing order
+	priors  []skills.Rating
+	ranks   []int
+	byRanks []int // The ranks being sorted by
+}
+
+func (b *FFABuffers) Len() int           { return len(b.order) }
+func (b *FFABuffers) Less(i, j int) bool { return b.byRanks[b.order[i]] < b.byRanks[b.order[j]] }
+func (b *FFABuffers) Swap(i, j int)      { b.order[i], b.order[j] = b.order[j], b.order[i] }
+
+// Calculates new ratings for a free-for-all match like FFACalc with the
+// default number of sweeps, using the buffers.
+//
+// Player i has skill mean means[i] and variance vars[i]. The players are split
+// into teams by teamStart as for TwoTeamFlat, with any number of teams; nil
+// makes every player a team of their own. ranks holds the rank of each team,
+// as for CalcNewRatings. A team's performance is the sum of its players', as
+// in TwoTeamCalc, and teams of one are rated exactly as FFACalc rates them.
+// The posterior means and variances are written to newMeans and newVars,
+// which may be means and vars themselves. Every player uses the game's beta,
+// every place is known and the match has a weight of 1.
+func FFAFlat(gi *skills.GameInfo, means, vars []float64, teamStart, ranks []int, newMeans, newVars []float64, buf *FFABuffers) {
+	n := len(means)
+	teams := len(teamStart)
+	if teamStart == nil {
+		teams = n
+	}
+	if len(vars) != n || len(ranks) != teams || len(newMeans) != n || len(newVars) != n {
+		panic(fmt.Errorf("slices of %v means, %v variances, %v ranks of %v teams, %v new means and %v new variances", n, len(vars), len(ranks), teams, len(newMeans), len(newVars)))
+	}
+	if teams < 2 {
+		panic(fmt.Errorf("free-for-all match of %v teams", teams))
+	}
+	for t, start := range teamStart {
+		if (t == 0) != (start == 0) || start >= n || t > 0 && start <= teamStart[t-1] {
+			panic(fmt.Errorf("team starts %v do not split %v players into teams", teamStart, n))
+		}
+	}
+
+	// Put the teams in finishing order, keeping the given order of ties
+	buf.order = resizeInts(buf.order, teams)
+	for t := range buf.order {
+		buf.order[t] = t
+	}
+	buf.byRanks = ranks
+	sort.Stable(buf)
+	buf.byRanks = nil
+
+	// The chain compares the teams' performances. A team's rating is made to
+	// give the variance of its performance, the sum of its players' skill,
+	// dynamics and performance variances, once the chain adds the dynamics
+	// and performance variances of a single player.
+	if cap(buf.priors) < teams {
+		buf.priors = make([]skills.Rating, teams)
+	}
+	buf.priors = buf.priors[:teams]
+	buf.ranks = resizeInts(buf.ranks, teams)
+	tauSqr := numerics.Sqr(gi.DynamicsFactor)
+	for k, t := range buf.order {
+		start, end := flatTeam(teamStart, t, n)
+		mean, variance := 0.0, float64(end-start-1)*tauSqr
+		for i := start; i < end; i++ {
+			mean += means[i]
+			variance += vars[i]
+		}
+		buf.priors[k] = skills.NewRating(mean, math.Sqrt(variance))
+		if end-start > 1 {
+			buf.priors[k] = buf.priors[k].WithBeta(math.Sqrt(float64(end-start)) * gi.Beta)
+		}
+		buf.ranks[k] = ranks[t]
+	}
+
+	c := &buf.chain
+	c.reset(gi, buf.priors, buf.ranks, teams, 1)
+	for i := 0; i < DefaultFFASweeps && c.sweep() > ffaTolerance; i++ {
+	}
+	for k, t := range buf.order {
+		start, end := flatTeam(teamStart, t, n)
+		if end-start == 1 {
+			r := c.posterior(k)
+			newMeans[start], newVars[start] = r.Mean(), r.Variance()
+			continue
+		}
+		for i := start; i < end; i++ {
+			newMeans[i], newVars[i] = c.teamPosterior(k, means[i], vars[i])
+		}
+	}
+}
+
+// Returns the players of team t of n players split by teamStart (see
+// FFAFlat).
+func flatTeam(teamStart []int, t, n int) (start, end int) {
+	if teamStart == nil {
+		return t, t + 1
+	}
+	if t+1 < len(teamStart) {
+		return teamStart[t], teamStart[t+1]
+	}
+	return teamStart[t], n
+}
+
+// Returns s resized to n elements, reusing its array if it is large enough.
+func resizeInts(s []int, n int) []int {
+	if cap(s) < n {
+		return make([]int, n)
+	}
+	return s[:n]
+}
+
+func resizeFloats(s []float64, n int) []float64 {
+	if cap(s) < n {
+		return make([]float64, n)
+	}
+	return s[:n]
+}
@@ -0,0 +1,173 @@
+package trueskill
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"testing"
+)
+
+var (
+	flatMeans     = []float64{25, 30, 18, 41, 25}
+	flatVars      = []float64{69, 4, 16, 9, 25}
+	flatTeamStart = []int{0, 2}
+)
+
+// Returns the players of flatMeans and flatVars as teams
+func flatTeams() []skills.Team {
+	teams := []skills.Team{skills.NewTeam(), skills.NewTeam()}
+	for i := range flatMeans {
+		teams[cond(i < flatTeamStart[1], 0, 1)].AddPlayer(*skills.NewPlayer(i), skills.NewRating(flatMeans[i], math.Sqrt(flatVars[i])))
+	}
+	return teams
+}
+
+func TestTwoTeamFlat(t *testing.T) {
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.Advantage = 2
+
+	newMeans := make([]float64, len(flatMeans))
+	newVars := make([]float64, len(flatMeans))
+	for _, ranks := range [][]int{{1, 2}, {1, 1}, {2, 1}} {
+		TwoTeamFlat(&gameInfo, flatMeans, flatVars, flatTeamStart, ranks, newMeans, newVars)
+		want := (&TwoTeamCalc{}).CalcNewRatings(&gameInfo, flatTeams(), ranks...)
+		for i := range flatMeans {
+			w := want[*skills.NewPlayer(i)]
+			if math.Abs(newMeans[i]-w.Mean()) > 1e-12 || math.Abs(newVars[i]-w.Variance()) > 1e-12 {
+				t.Errorf("ranks %v: player %v = %v, %v, want %v", ranks, i, newMeans[i], newVars[i], w)
+			}
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		TwoTeamFlat(&gameInfo, flatMeans, flatVars, flatTeamStart, []int{2, 1}, newMeans, newVars)
+	})
+	if allocs != 0 {
+		t.Errorf("TwoTeamFlat allocated %v times per run", allocs)
+	}
+
+	// The posteriors may overwrite the priors
+	means := append([]float64{}, flatMeans...)
+	vars := append([]float64{}, flatVars...)
+	TwoTeamFlat(&gameInfo, means, vars, flatTeamStart, []int{2, 1}, means, vars)
+	for i := range means {
+		if means[i] != newMeans[i] || vars[i] != newVars[i] {
+			t.Errorf("player %v rated in place = %v, %v, want %v, %v", i, means[i], vars[i], newMeans[i], newVars[i])
+		}
+	}
+}
+
+func TestFFAFlat(t *testing.T) {
+	gameInfo := skills.DefaultGameInfo
+	var buf FFABuffers
+
+	newMeans := make([]float64, len(flatMeans))
+	newVars := make([]float64, len(flatMeans))
+	for _, ranks := range [][]int{{1, 2, 3, 4, 5}, {3, 1, 3, 2, 1}, {2, 2, 2, 2, 2}} {
+		FFAFlat(gameInfo, flatMeans, flatVars, nil, ranks, newMeans, newVars, &buf)
+
+		teams := make([]skills.Team, len(flatMeans))
+		for i := range teams {
+			teams[i] = skills.NewTeam()
+			teams[i].AddPlayer(*skills.NewPlayer(i), skills.NewRating(flatMeans[i], math.Sqrt(flatVars[i])))
+		}
+		want := (&FFACalc{}).CalcNewRatings(gameInfo, teams, ranks...)
+		for i := range flatMeans {
+			w := want[*skills.NewPlayer(i)]
+			if math.Abs(newMeans[i]-w.Mean()) > 1e-12 || math.Abs(newVars[i]-w.Variance()) > 1e-12 {
+				t.Errorf("ranks %v: player %v = %v, %v, want %v", ranks, i, newMeans[i], newVars[i], w)
+			}
+		}
+	}
+
+	// Smaller matches reuse the buffers
+	ranks := []int{2, 1, 3}
+	allocs := testing.AllocsPerRun(100, func() {
+		FFAFlat(gameInfo, flatMeans[:3], flatVars[:3], nil, ranks, newMeans[:3], newVars[:3], &buf)
+	})
+	if allocs != 0 {
+		t.Errorf("FFAFlat allocated %v times per run", allocs)
+	}
+}
+
+func TestFFAFlatTeams(t *testing.T) {
+	gameInfo := skills.DefaultGameInfo
+	var buf FFABuffers
+	newMeans := make([]float64, len(flatMeans))
+	newVars := make([]float64, len(flatMeans))
+
+	// Two teams are rated as by TwoTeamFlat, whose single comparison the
+	// chain gets exactly. The chain counts the dynamics in the variance of
+	// the performance difference, as FFACalc does and TwoTeamCalc does not,
+	// so they are left out.
+	static := *gameInfo
+	static.DynamicsFactor = 0
+	want := make([]float64, 2*len(flatMeans))
+	for _, ranks := range [][]int{{1, 2}, {1, 1}, {2, 1}} {
+		FFAFlat(&static, flatMeans, flatVars, flatTeamStart, ranks, newMeans, newVars, &buf)
+		TwoTeamFlat(&static, flatMeans, flatVars, flatTeamStart, ranks, want[:len(flatMeans)], want[len(flatMeans):])
+		for i := range flatMeans {
+			if math.Abs(newMeans[i]-want[i]) > 1e-9 || math.Abs(newVars[i]-want[len(flatMeans)+i]) > 1e-9 {
+				t.Errorf("ranks %v: player %v = %v, %v, want %v, %v", ranks, i, newMeans[i], newVars[i], want[i], want[len(flatMeans)+i])
+			}
+		}
+	}
+
+	// Teams of one are rated as without teams
+	single := []int{0, 1, 2, 3, 4}
+	FFAFlat(gameInfo, flatMeans, flatVars, single, []int{3, 1, 3, 2, 1}, newMeans, newVars, &buf)
+	FFAFlat(gameInfo, flatMeans, flatVars, nil, []int{3, 1, 3, 2, 1}, want[:len(flatMeans)], want[len(flatMeans):], &buf)
+	for i := range flatMeans {
+		if newMeans[i] != want[i] || newVars[i] != want[len(flatMeans)+i] {
+			t.Errorf("player %v in a team of one = %v, %v, want %v, %v", i, newMeans[i], newVars[i], want[i], want[len(flatMeans)+i])
+		}
+	}
+
+	// Three teams: the winners gain, the losers lose
+	FFAFlat(gameInfo, flatMeans, flatVars, []int{0, 2, 3}, []int{1, 2, 3}, newMeans, newVars, &buf)
+	for i, gains := range []bool{true, true, false, false, false} {
+		if i == 2 {
+			continue
+		}
+		if (newMeans[i] > flatMeans[i]) != gains || newVars[i] >= flatVars[i]+numerics.Sqr(gameInfo.DynamicsFactor) {
+			t.Errorf("player %v of three teams = %v, %v from %v, %v", i, newMeans[i], newVars[i], flatMeans[i], flatVars[i])
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("unordered team starts did not panic")
+		}
+	}()
+	FFAFlat(gameInfo, flatMeans, flatVars, []int{0, 3, 2}, []int{1, 2, 3}, newMeans, newVars, &buf)
+}
+
+func BenchmarkTwoTeamFlat(b *testing.B) {
+	newMeans := make([]float64, len(flatMeans))
+	newVars := make([]float64, len(flatMeans))
+	ranks := []int{1, 2}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		TwoTeamFlat(skills.DefaultGameInfo, flatMeans, flatVars, flatTeamStart, ranks, newMeans, newVars)
+	}
+}
+
+func BenchmarkTwoTeamCalc(b *testing.B) {
+	teams := flatTeams()
+	calc := &TwoTeamCalc{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calc.CalcNewRatings(skills.DefaultGameInfo, teams, 1, 2)
+	}
+}
+
+func BenchmarkFFAFlat(b *testing.B) {
+	newMeans := make([]float64, len(flatMeans))
+	newVars := make([]float64, len(flatMeans))
+	ranks := []int{1, 2, 3, 4, 5}
+	var buf FFABuffers
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		FFAFlat(skills.DefaultGameInfo, flatMeans, flatVars, nil, ranks, newMeans, newVars, &buf)
+	}
+}
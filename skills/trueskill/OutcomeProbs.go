@@ -0,0 +1,47 @@
+package trueskill
+
+import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"math"
+)
+
+// Calculates the outcome probabilities of a match between two teams. The
+// team performance difference has mean equal to the difference of the mean
+// sums and variance c², the same c used when updating ratings; a draw is a
+// difference within the draw margin. The first team's performance includes
+// the game's fixed advantage. Outliers are random outcomes with the game's
+// draw probability.
+func twoTeamOutcomeProbs(gi *skills.GameInfo, team1, team2 skills.Team) (win, draw, lose float64) {
+	drawMargin := gameDrawMargin(gi)
+
+	meanDelta := team1.Accum(skills.MeanSum) + gi.Advantage - team2.Accum(skills.MeanSum)
+	c := math.Sqrt(team1.Accum(skills.VarianceSum) + team2.Accum(skills.VarianceSum) + team1.Accum(gi.PerformanceVarianceSum) + team2.Accum(gi.PerformanceVarianceSum))
+
+	curve := gameCurve(gi)
+	win = curve.Cdf((meanDelta - drawMargin) / c)
+	lose = curve.Cdf((-meanDelta - drawMargin) / c)
+
+	a, b := outlierWeights(gi, false)
+	win = a*win + b
+	lose = a*lose + b
+	draw = 1 - win - lose
+	return
+}
+
+// Calculates the probabilities that the first player wins, that the players draw and that the first player loses.
+func (calc *TwoPlayerCalc) CalcOutcomeProbs(gi *skills.GameInfo, teams []skills.Team) (win, draw, lose float64) {
+	validateTeamCount(teams, twoPlayerTeamRange)
+	validatePlayersPerTeam(teams, twoPlayerPlayerRange)
+	teams = anchoredTeams(teams)
+
+	return twoTeamOutcomeProbs(gi, teams[0], teams[1])
+}
+
+// Calculates the probabilities that the first team wins, that the teams draw and that the first team loses.
+func (calc *TwoTeamCalc) CalcOutcomeProbs(gi *skills.GameInfo, teams []skills.Team) (win, draw, lose float64) {
+	validateTeamCount(teams, twoTeamTeamRange)
+	validatePlayersPerTeam(teams, twoTeamPlayerRange)
+	teams = anchoredTeams(teams)
+
+	return twoTeamOutcomeProbs(gi, teams[0], teams[1])
+}
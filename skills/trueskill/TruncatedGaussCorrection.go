@@ -1,12 +1,19 @@
 package trueskill
 
 import (
+	"github.com/ChrisHines/GoSkills/skills"
 	"github.com/ChrisHines/GoSkills/skills/numerics"
 	"math"
 )
 
 // These functions from the bottom of page 4 of the TrueSkill paper.
 
+// The standard normal Cdf. Unlike GaussCumulativeTo it keeps its relative
+// accuracy in the lower tail, where the corrections divide by it.
+func gaussCdf(x float64) float64 {
+	return numerics.GaussCurve{}.Cdf(x)
+}
+
 // The "V" function where the team performance difference is greater than the draw margin.
 // In the reference F# implementation, this is referred to as "the additive
 // correction of a single-sided truncated Gaussian with unit variance."
@@ -16,7 +23,7 @@ func vExceedsMarginC(perfDiff, drawMargin, c float64) float64 {
 }
 
 func vExceedsMargin(perfDiff, drawMargin float64) float64 {
-	denom := numerics.GaussCumulativeTo(perfDiff - drawMargin)
+	denom := gaussCdf(perfDiff - drawMargin)
 	if denom < 2.222758749e-162 {
 		return -perfDiff + drawMargin
 	}
@@ -31,7 +38,7 @@ func wExceedsMarginC(perfDiff, drawMargin, c float64) float64 {
 }
 
 func wExceedsMargin(perfDiff, drawMargin float64) float64 {
-	denom := numerics.GaussCumulativeTo(perfDiff - drawMargin)
+	denom := gaussCdf(perfDiff - drawMargin)
 	if denom < 2.222758749e-162 {
 		if perfDiff < 0.0 {
 			return 1.0
@@ -51,7 +58,7 @@ func vWithinMarginC(perfDiff, drawMargin, c float64) float64 {
 // from F#:
 func vWithinMargin(perfDiff, drawMargin float64) float64 {
 	perfDiffAbs := math.Abs(perfDiff)
-	denom := numerics.GaussCumulativeTo(drawMargin-perfDiffAbs) - numerics.GaussCumulativeTo(-drawMargin-perfDiffAbs)
+	denom := gaussCdf(drawMargin-perfDiffAbs) - gaussCdf(-drawMargin-perfDiffAbs)
 	if denom < 2.222758749e-162 {
 		if perfDiff < 0.0 {
 			return -perfDiff - drawMargin
@@ -74,7 +81,7 @@ func wWithinMarginC(perfDiff, drawMargin, c float64) float64 {
 // From F#:
 func wWithinMargin(perfDiff, drawMargin float64) float64 {
 	perfDiffAbs := math.Abs(perfDiff)
-	denom := numerics.GaussCumulativeTo(drawMargin-perfDiffAbs) - numerics.GaussCumulativeTo(-drawMargin-perfDiffAbs)
+	denom := gaussCdf(drawMargin-perfDiffAbs) - gaussCdf(-drawMargin-perfDiffAbs)
 
 	if denom < 2.222758749e-162 {
 		return 1.0
@@ -83,3 +90,130 @@ func wWithinMargin(perfDiff, drawMargin float64) float64 {
 
 	return vt*vt + ((drawMargin-perfDiffAbs)*numerics.GaussAt(drawMargin-perfDiffAbs)-(-drawMargin-perfDiffAbs)*numerics.GaussAt(-drawMargin-perfDiffAbs))/denom
 }
+
+// Returns the additive and multiplicative corrections for a match whose
+// performance difference of winner over loser (or of one side over the other
+// for a draw) has mean perfDiff and standard deviation c.
+//
+// The multiplicative correction is clamped to [0, 1]. Moment matching keeps
+// it there for the Gaussian, but the curvature of other curves' likelihoods
+// can take it past 1 (e.g. for a draw under the logistic curve with a small
+// margin), which would make posterior variances negative; at 1 they shrink to
+// at most the variance of the rest of the difference, which is positive.
+func corrections(gi *skills.GameInfo, perfDiff, drawMargin, c float64, wasDraw bool) (v, w float64) {
+	v, w = gameCorrections(gi, perfDiff, drawMargin, c, wasDraw)
+	return v, math.Min(math.Max(w, 0), 1)
+}
+
+func gameCorrections(gi *skills.GameInfo, perfDiff, drawMargin, c float64, wasDraw bool) (v, w float64) {
+	if _, gauss := gameCurve(gi).(numerics.GaussCurve); !gauss || gi.OutlierProbability > 0 {
+		curve := gameCurve(gi)
+		a, b := outlierWeights(gi, wasDraw)
+		if wasDraw {
+			return vWithinMarginCurve(curve, perfDiff/c, drawMargin/c, a, b), wWithinMarginCurve(curve, perfDiff/c, drawMargin/c, a, b)
+		}
+		return vExceedsMarginCurve(curve, perfDiff/c, drawMargin/c, a, b), wExceedsMarginCurve(curve, perfDiff/c, drawMargin/c, a, b)
+	}
+	if gi.TabulatedCorrections {
+		if wasDraw {
+			return vWithinMarginTable(perfDiff/c, drawMargin/c), wWithinMarginTable(perfDiff/c, drawMargin/c)
+		}
+		return vExceedsMarginTable(perfDiff/c, drawMargin/c), wExceedsMarginTable(perfDiff/c, drawMargin/c)
+	}
+	if wasDraw {
+		return vWithinMarginC(perfDiff, drawMargin, c), wWithinMarginC(perfDiff, drawMargin, c)
+	}
+	return vExceedsMarginC(perfDiff, drawMargin, c), wExceedsMarginC(perfDiff, drawMargin, c)
+}
+
+// Returns the game's curve, the Gaussian if it has none.
+func gameCurve(gi *skills.GameInfo) numerics.Curve {
+	if gi.Curve == nil {
+		return numerics.GaussCurve{}
+	}
+	return gi.Curve
+}
+
+// Returns the weights of the likelihood of an outcome that is an outlier
+// with the game's OutlierProbability: a times the curve's plus b, the
+// probability of a random outcome. A random outcome is a draw with the
+// game's draw probability and otherwise equally likely a win or a loss.
+func outlierWeights(gi *skills.GameInfo, wasDraw bool) (a, b float64) {
+	p := gi.OutlierProbability
+	if wasDraw {
+		return 1 - p, p * gi.DrawProbability
+	}
+	return 1 - p, p * (1 - gi.DrawProbability) / 2
+}
+
+// The corrections for the likelihood a·F(t) + b of a win, where F is the
+// curve's Cdf and t = perfDiff - drawMargin:
+//
+//	v = a·f(t) / (a·F(t) + b)
+//	w = v² - a·f'(t) / (a·F(t) + b)
+//
+// For the Gaussian with a = 1 and b = 0 these are vExceedsMargin and
+// wExceedsMargin. With outliers the normalizer never vanishes and the
+// corrections fade away for massive upsets. Without them it underflows in
+// the far tail, where the corrections take the Gaussian's limits as in
+// vExceedsMargin; heavier tailed curves only get there hundreds of standard
+// deviations out.
+func vExceedsMarginCurve(curve numerics.Curve, perfDiff, drawMargin, a, b float64) float64 {
+	t := perfDiff - drawMargin
+	z := a*curve.Cdf(t) + b
+	if z < 2.222758749e-162 {
+		return -t
+	}
+	return a * curve.Pdf(t) / z
+}
+
+func wExceedsMarginCurve(curve numerics.Curve, perfDiff, drawMargin, a, b float64) float64 {
+	t := perfDiff - drawMargin
+	z := a*curve.Cdf(t) + b
+	if z < 2.222758749e-162 {
+		if perfDiff < 0 {
+			return 1
+		}
+		return 0
+	}
+	v := vExceedsMarginCurve(curve, perfDiff, drawMargin, a, b)
+	return v*v - a*curve.PdfDeriv(t)/z
+}
+
+// The corrections for the likelihood Z = a·(F(β) - F(α)) + b of a draw,
+// with α = -drawMargin - perfDiff and β = drawMargin - perfDiff:
+//
+//	v = a·(f(α) - f(β)) / Z
+//	w = v² - a·(f'(β) - f'(α)) / Z
+//
+// Where Z underflows they take the Gaussian's limits as in vWithinMargin.
+func vWithinMarginCurve(curve numerics.Curve, perfDiff, drawMargin, a, b float64) float64 {
+	alpha, beta := -drawMargin-perfDiff, drawMargin-perfDiff
+	z := a*curveMass(curve, alpha, beta) + b
+	if z < 2.222758749e-162 {
+		if perfDiff < 0 {
+			return -perfDiff - drawMargin
+		}
+		return -perfDiff + drawMargin
+	}
+	return a * (curve.Pdf(alpha) - curve.Pdf(beta)) / z
+}
+
+func wWithinMarginCurve(curve numerics.Curve, perfDiff, drawMargin, a, b float64) float64 {
+	alpha, beta := -drawMargin-perfDiff, drawMargin-perfDiff
+	z := a*curveMass(curve, alpha, beta) + b
+	if z < 2.222758749e-162 {
+		return 1
+	}
+	v := vWithinMarginCurve(curve, perfDiff, drawMargin, a, b)
+	return v*v - a*(curve.PdfDeriv(beta)-curve.PdfDeriv(alpha))/z
+}
+
+// Returns F(β) - F(α), taken from the lower tail by symmetry when both are in
+// the upper tail so the difference does not cancel.
+func curveMass(curve numerics.Curve, alpha, beta float64) float64 {
+	if alpha > 0 {
+		return curve.Cdf(-alpha) - curve.Cdf(-beta)
+	}
+	return curve.Cdf(beta) - curve.Cdf(alpha)
+}
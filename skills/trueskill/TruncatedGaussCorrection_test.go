@@ -0,0 +1,103 @@
+package trueskill
+
+import (
+	"fmt"
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"testing"
+)
+
+// The corrections are the first and negated second derivatives of the log
+// of the outcome's likelihood with respect to the performance difference.
+func assertCorrections(t *testing.T, name string, logZ, v, w func(x float64) float64) {
+	const h = 1e-4
+	for x := -6.0; x <= 6; x += 0.5 {
+		dv := (logZ(x+h) - logZ(x-h)) / (2 * h)
+		dw := -(logZ(x+h) - 2*logZ(x) + logZ(x-h)) / (h * h)
+		if math.Abs(v(x)-dv) > 1e-6 {
+			t.Errorf("%v: v(%v) = %v, want %v", name, x, v(x), dv)
+		}
+		if math.Abs(w(x)-dw) > 1e-4 {
+			t.Errorf("%v: w(%v) = %v, want %v", name, x, w(x), dw)
+		}
+	}
+}
+
+func TestCurveCorrections(t *testing.T) {
+	const eps = 0.7
+
+	for _, curve := range []numerics.Curve{numerics.GaussCurve{}, numerics.LogisticCurve{}} {
+		for _, ab := range [][2]float64{{1, 0}, {0.95, 0.02}} {
+			a, b := ab[0], ab[1]
+			name := fmt.Sprintf("%T a=%v b=%v", curve, a, b)
+
+			assertCorrections(t, name+" win",
+				func(x float64) float64 { return math.Log(a*curve.Cdf(x-eps) + b) },
+				func(x float64) float64 { return vExceedsMarginCurve(curve, x, eps, a, b) },
+				func(x float64) float64 { return wExceedsMarginCurve(curve, x, eps, a, b) })
+
+			assertCorrections(t, name+" draw",
+				// The likelihood of a draw is even in x; keep its terms in the
+				// lower tail for accuracy
+				func(x float64) float64 {
+					return math.Log(a*(curve.Cdf(eps-math.Abs(x))-curve.Cdf(-eps-math.Abs(x))) + b)
+				},
+				func(x float64) float64 { return vWithinMarginCurve(curve, x, eps, a, b) },
+				func(x float64) float64 { return wWithinMarginCurve(curve, x, eps, a, b) })
+		}
+	}
+
+	// For the Gaussian without outliers they are the TrueSkill corrections
+	gauss := numerics.GaussCurve{}
+	for x := -4.0; x <= 4; x += 0.5 {
+		for _, d := range []float64{
+			vExceedsMarginCurve(gauss, x, eps, 1, 0) - vExceedsMargin(x, eps),
+			wExceedsMarginCurve(gauss, x, eps, 1, 0) - wExceedsMargin(x, eps),
+			vWithinMarginCurve(gauss, x, eps, 1, 0) - vWithinMargin(x, eps),
+			wWithinMarginCurve(gauss, x, eps, 1, 0) - wWithinMargin(x, eps),
+		} {
+			if math.Abs(d) > 1e-9 {
+				t.Errorf("corrections at %v differ from TrueSkill's by %v", x, d)
+			}
+		}
+	}
+}
+
+func TestExtremeCorrections(t *testing.T) {
+	// Past the underflow of the likelihood the Gaussian curve takes the same
+	// limits as the TrueSkill corrections
+	gauss := numerics.GaussCurve{}
+	for _, x := range []float64{-45, -38, -30, -20, 20, 30, 45} {
+		for _, d := range []float64{
+			vExceedsMarginCurve(gauss, x, 0.1, 1, 0) - vExceedsMargin(x, 0.1),
+			wExceedsMarginCurve(gauss, x, 0.1, 1, 0) - wExceedsMargin(x, 0.1),
+			vWithinMarginCurve(gauss, x, 0.1, 1, 0) - vWithinMargin(x, 0.1),
+			wWithinMarginCurve(gauss, x, 0.1, 1, 0) - wWithinMargin(x, 0.1),
+		} {
+			if !(math.Abs(d) <= 1e-6) {
+				t.Errorf("corrections at %v differ from TrueSkill's by %v", x, d)
+			}
+		}
+	}
+
+	// Massive upsets rate the same with the Gaussian curve as without one
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.Curve = numerics.GaussCurve{}
+	for _, calc := range []skills.Calc{&TwoPlayerCalc{}, &TwoTeamCalc{}, &FFACalc{}} {
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*skills.NewPlayer(1), skills.NewRating(0, 8))
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*skills.NewPlayer(2), skills.NewRating(300, 8))
+		teams := []skills.Team{team1, team2}
+
+		for _, ranks := range [][]int{{1, 2}, {1, 1}} {
+			want := calc.CalcNewRatings(skills.DefaultGameInfo, teams, ranks...)
+			for p, r := range calc.CalcNewRatings(&gameInfo, teams, ranks...) {
+				if math.IsNaN(r.Mean()) || math.IsNaN(r.Stddev()) || !r.Equal(want[p]) {
+					t.Errorf("%T: rating of %v after %v = %v, want %v", calc, p, ranks, r, want[p])
+				}
+			}
+		}
+	}
+}
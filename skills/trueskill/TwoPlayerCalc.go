@@ -14,15 +14,28 @@ type TwoPlayerCalc struct{}
 
 // Calculates new ratings based on the prior ratings and team ranks use 1 for first place, repeat the number for a tie (e.g. 1, 2, 2).
 func (calc *TwoPlayerCalc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return calc.CalcMatch(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Calculates new ratings for the players of the match.
+func (calc *TwoPlayerCalc) CalcMatch(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	newSkills, _ := calc.CalcUpdate(gi, m)
+	return newSkills
+}
+
+// Calculates new ratings for the players of the match like CalcMatch, along
+// with the priors they were updated from (see skills.UpdateCalc).
+func (calc *TwoPlayerCalc) CalcUpdate(gi *skills.GameInfo, m *skills.Match) (skills.PlayerRatings, skills.PlayerRatings) {
 	newSkills := make(map[skills.Player]skills.Rating)
 
 	// Basic argument checking
-	validateTeamCount(teams, twoPlayerTeamRange)
-	validatePlayersPerTeam(teams, twoPlayerPlayerRange)
+	validateTeamCount(m.Teams, twoPlayerTeamRange)
+	validatePlayersPerTeam(m.Teams, twoPlayerPlayerRange)
+	validateWeight(m.MatchWeight())
 
 	// Copy the slices so we don't confuse the client code
-	steams := append([]skills.Team{}, teams...)
-	sranks := append([]int{}, ranks...)
+	steams := append([]skills.Team{}, anchoredTeams(gi.InactivePriors(m.Teams, m.Time))...)
+	sranks := append([]int{}, m.Ranks...)
 
 	// Make sure things are in order
 	sort.Sort(skills.NewRankedTeams(steams, sranks))
@@ -38,77 +51,95 @@ func (calc *TwoPlayerCalc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Te
 
 	wasDraw := sranks[0] == sranks[1]
 
-	newSkills[winner] = twoPlayerCalcNewRating(gi, winnerPrevRating, loserPrevRating, cond(wasDraw, skills.Draw, skills.Win))
-	newSkills[loser] = twoPlayerCalcNewRating(gi, loserPrevRating, winnerPrevRating, cond(wasDraw, skills.Draw, skills.Lose))
+	// The advantage of the winner over the loser
+	adv := sortedAdvantage(m.FirstTeamAdvantage(gi), m.Ranks)
 
-	return newSkills
+	winnerWeight, loserWeight := m.MatchWeight(), m.MatchWeight()
+	if gi.Leavers != nil {
+		winnerWeight *= gi.Leavers.Weight(winningTeam, losingTeam)
+		loserWeight *= gi.Leavers.Weight(losingTeam, winningTeam)
+	}
+
+	winnerNewRating, winnerAdv := twoPlayerCalcNewRating(gi, winnerPrevRating, loserPrevRating, adv, cond(wasDraw, skills.Draw, skills.Win), winnerWeight)
+	loserNewRating, loserAdv := twoPlayerCalcNewRating(gi, loserPrevRating, winnerPrevRating, negated(adv), cond(wasDraw, skills.Draw, skills.Lose), loserWeight)
+	setAdvantage(m, winnerAdv, loserAdv)
+
+	// A leaver is rated as having lost, whatever the result
+	if gi.Leavers != nil {
+		if _, left := winningTeam.LeftAt(); left {
+			winnerNewRating, _ = twoPlayerCalcNewRating(gi, winnerPrevRating, loserPrevRating, adv, skills.Lose, m.MatchWeight())
+			winnerWeight = m.MatchWeight()
+		}
+		if _, left := losingTeam.LeftAt(); left {
+			loserNewRating, _ = twoPlayerCalcNewRating(gi, loserPrevRating, winnerPrevRating, negated(adv), skills.Lose, m.MatchWeight())
+			loserWeight = m.MatchWeight()
+		}
+	}
+
+	newSkills[winner] = stamped(winnerNewRating, winnerPrevRating, m.Time)
+	newSkills[loser] = stamped(loserNewRating, loserPrevRating, m.Time)
+
+	keepAnchors(newSkills, m.Teams)
+	priors := skills.PlayerRatings{
+		winner: withDynamics(gi, winnerPrevRating, winnerWeight),
+		loser:  withDynamics(gi, loserPrevRating, loserWeight),
+	}
+	return newSkills, priors
 }
 
-func twoPlayerCalcNewRating(gi *skills.GameInfo, selfRating, oppRating skills.Rating, comparison int) skills.Rating {
-	drawMargin := drawMarginFromDrawProbability(gi.DrawProbability, gi.Beta)
+// Calculates the new rating of self, where adv is self's advantage over the
+// opponent, along with the posterior of that advantage, for a match of the
+// given weight.
+func twoPlayerCalcNewRating(gi *skills.GameInfo, selfRating, oppRating, adv skills.Rating, comparison int, weight float64) (skills.Rating, skills.Rating) {
+	drawMargin := gameDrawMargin(gi)
 
-	c := math.Sqrt(numerics.Sqr(selfRating.Stddev()) + numerics.Sqr(oppRating.Stddev()) + 2*numerics.Sqr(gi.Beta))
+	c := math.Sqrt(numerics.Sqr(selfRating.Stddev()) + numerics.Sqr(oppRating.Stddev()) + gi.PerformanceVariance(selfRating) + gi.PerformanceVariance(oppRating) + adv.Variance())
 
-	winningMean := selfRating.Mean()
+	winningMean := selfRating.Mean() + adv.Mean()
 	losingMean := oppRating.Mean()
 
 	if comparison == skills.Lose {
-		winningMean = oppRating.Mean()
-		losingMean = selfRating.Mean()
+		winningMean, losingMean = losingMean, winningMean
 	}
 
 	meanDelta := winningMean - losingMean
 
-	var v, w, rankMultiplier float64
+	v, w := corrections(gi, meanDelta, drawMargin, c, comparison == skills.Draw)
 
+	rankMultiplier := 1.0
 	if comparison != skills.Draw {
-		v = vExceedsMarginC(meanDelta, drawMargin, c)
-		w = wExceedsMarginC(meanDelta, drawMargin, c)
 		rankMultiplier = float64(comparison)
-	} else {
-		v = vWithinMarginC(meanDelta, drawMargin, c)
-		w = wWithinMarginC(meanDelta, drawMargin, c)
-		rankMultiplier = 1
 	}
 
-	meanMultiplier := (numerics.Sqr(selfRating.Stddev()) + numerics.Sqr(gi.DynamicsFactor)) / c
+	meanMultiplier := (numerics.Sqr(selfRating.Stddev()) + dynamicsVariance(gi, weight)) / c
 
-	varianceWithDynamics := numerics.Sqr(selfRating.Stddev()) + numerics.Sqr(gi.DynamicsFactor)
+	varianceWithDynamics := numerics.Sqr(selfRating.Stddev()) + dynamicsVariance(gi, weight)
 	stdDevMultiplier := varianceWithDynamics / numerics.Sqr(c)
 
 	newMean := selfRating.Mean() + (rankMultiplier * meanMultiplier * v)
 	newStdDev := math.Sqrt(varianceWithDynamics * (1 - w*stdDevMultiplier))
 
-	return skills.NewRating(newMean, newStdDev)
+	newRating := temperedRating(selfRating.Mean(), varianceWithDynamics, skills.NewRating(newMean, newStdDev), weight)
+	return newRating, temperedRating(adv.Mean(), adv.Variance(), advantagePosterior(adv, v, w, c, rankMultiplier), weight)
 }
 
 // Calculates the match quality as the likelihood of all teams drawing (0% = bad, 100% = well matched).
+// The first player's performance includes the game's fixed advantage.
 func (calc *TwoPlayerCalc) CalcMatchQual(gi *skills.GameInfo, teams []skills.Team) float64 {
 	validateTeamCount(teams, twoPlayerTeamRange)
 	validatePlayersPerTeam(teams, twoPlayerPlayerRange)
 
-	team1 := teams[0]
-	p1 := team1.Players()[0]
-	p1Rating := team1.PlayerRating(p1)
-
-	team2 := teams[1]
-	p2 := team2.Players()[0]
-	p2Rating := team2.PlayerRating(p2)
-
-	// We just use equation 4.1 found on page 8 of the TrueSkill 2006 paper:
-	betaSqr := numerics.Sqr(gi.Beta)
-	p1var := p1Rating.Variance()
-	p2var := p2Rating.Variance()
-
-	// This is the square root part of the equation:
-	sqrtPart := math.Sqrt(2 * betaSqr / (2*betaSqr + p1var + p2var))
-
-	// This is the exponent part of the equation:
-	numerator := -numerics.Sqr(p1Rating.Mean() - p2Rating.Mean())
-	denominator := 2 * (2*betaSqr + p1var + p2var)
-	expPart := math.Exp(numerator / denominator)
+	// Equation 4.1 found on page 8 of the TrueSkill 2006 paper, as for two
+	// teams
+	return twoTeamMatchQual(gi, teams, skills.NewRating(gi.Advantage, 0))
+}
 
-	return sqrtPart * expPart
+// Calculates the match quality from the ratings CalcMatch rates the match
+// from, with the first player's advantage the match's own if it has one.
+func (calc *TwoPlayerCalc) MatchQual(gi *skills.GameInfo, m *skills.Match) float64 {
+	validateTeamCount(m.Teams, twoPlayerTeamRange)
+	validatePlayersPerTeam(m.Teams, twoPlayerPlayerRange)
+	return twoTeamMatchQual(gi, gi.InactivePriors(m.Teams, m.Time), m.FirstTeamAdvantage(gi))
 }
 
 var (
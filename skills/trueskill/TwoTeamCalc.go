@@ -5,6 +5,7 @@ import (
 	"github.com/ChrisHines/GoSkills/skills/numerics"
 	"math"
 	"sort"
+	"time"
 )
 
 // Calculates new ratings for only two teams where each team has 1 or more players.
@@ -13,15 +14,27 @@ type TwoTeamCalc struct{}
 
 // Calculates new ratings based on the prior ratings and team ranks use 1 for first place, repeat the number for a tie (e.g. 1, 2, 2).
 func (calc *TwoTeamCalc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Team, ranks ...int) skills.PlayerRatings {
+	return calc.CalcMatch(gi, skills.NewMatch(teams, ranks...))
+}
+
+// Calculates new ratings for the players of the match.
+func (calc *TwoTeamCalc) CalcMatch(gi *skills.GameInfo, m *skills.Match) skills.PlayerRatings {
+	return twoTeamCalcMatch(gi, m, nil)
+}
+
+// Calculates new ratings for the players of the match, and explains the
+// update of each player in explain if it is not nil.
+func twoTeamCalcMatch(gi *skills.GameInfo, m *skills.Match, explain map[skills.Player]*Explanation) skills.PlayerRatings {
 	newSkills := make(map[skills.Player]skills.Rating)
 
 	// Basic argument checking
-	validateTeamCount(teams, twoTeamTeamRange)
-	validatePlayersPerTeam(teams, twoTeamPlayerRange)
+	validateTeamCount(m.Teams, twoTeamTeamRange)
+	validatePlayersPerTeam(m.Teams, twoTeamPlayerRange)
+	validateWeight(m.MatchWeight())
 
 	// Copy slices so we don't confuse the client code
-	steams := append([]skills.Team{}, teams...)
-	sranks := append([]int{}, ranks...)
+	steams := append([]skills.Team{}, anchoredTeams(gi.InactivePriors(m.Teams, m.Time))...)
+	sranks := append([]int{}, m.Ranks...)
 
 	// Make sure things are in order
 	sort.Sort(skills.NewRankedTeams(steams, sranks))
@@ -31,23 +44,43 @@ func (calc *TwoTeamCalc) CalcNewRatings(gi *skills.GameInfo, teams []skills.Team
 
 	wasDraw := sranks[0] == sranks[1]
 
-	twoTeamUpdateRatings(gi, newSkills, winningTeam, losingTeam, cond(wasDraw, skills.Draw, skills.Win))
-	twoTeamUpdateRatings(gi, newSkills, losingTeam, winningTeam, cond(wasDraw, skills.Draw, skills.Lose))
+	// The advantage of the winning team over the losing team
+	adv := sortedAdvantage(m.FirstTeamAdvantage(gi), m.Ranks)
+
+	winnerWeight, loserWeight := m.MatchWeight(), m.MatchWeight()
+	if gi.Leavers != nil {
+		winnerWeight *= gi.Leavers.Weight(winningTeam, losingTeam)
+		loserWeight *= gi.Leavers.Weight(losingTeam, winningTeam)
+	}
+
+	winnerAdv := twoTeamUpdateRatings(gi, newSkills, explain, winningTeam, losingTeam, adv, cond(wasDraw, skills.Draw, skills.Win), winnerWeight, m.Time)
+	loserAdv := twoTeamUpdateRatings(gi, newSkills, explain, losingTeam, winningTeam, negated(adv), cond(wasDraw, skills.Draw, skills.Lose), loserWeight, m.Time)
+	setAdvantage(m, winnerAdv, loserAdv)
 
+	if gi.Leavers != nil {
+		twoTeamUpdateLeavers(gi, newSkills, explain, winningTeam, losingTeam, adv, m.MatchWeight(), m.Time)
+		twoTeamUpdateLeavers(gi, newSkills, explain, losingTeam, winningTeam, negated(adv), m.MatchWeight(), m.Time)
+	}
+
+	keepAnchors(newSkills, m.Teams)
+	for p, e := range explain {
+		e.Posterior = newSkills[p]
+	}
 	return newSkills
 }
 
-func twoTeamUpdateRatings(gi *skills.GameInfo, newSkills skills.PlayerRatings, selfTeam, otherTeam skills.Team, comparison int) {
-	drawMargin := drawMarginFromDrawProbability(gi.DrawProbability, gi.Beta)
-	betaSqr := numerics.Sqr(gi.Beta)
-	tauSqr := numerics.Sqr(gi.DynamicsFactor)
-
-	totalPlayers := selfTeam.PlayerCount() + otherTeam.PlayerCount()
+// Updates the ratings of selfTeam's players, where adv is selfTeam's advantage
+// over otherTeam, and returns the posterior of that advantage. The updates are
+// explained in explain if it is not nil.
+func twoTeamUpdateRatings(gi *skills.GameInfo, newSkills skills.PlayerRatings, explain map[skills.Player]*Explanation, selfTeam, otherTeam skills.Team, adv skills.Rating, comparison int, weight float64, at time.Time) skills.Rating {
+	drawMargin := gameDrawMargin(gi)
+	tauSqr := dynamicsVariance(gi, weight)
 
-	selfMeanSum := selfTeam.Accum(skills.MeanSum)
+	selfMeanSum := selfTeam.Accum(skills.MeanSum) + adv.Mean()
 	otherMeanSum := otherTeam.Accum(skills.MeanSum)
 
-	c := math.Sqrt(selfTeam.Accum(skills.VarianceSum) + otherTeam.Accum(skills.VarianceSum) + float64(totalPlayers)*betaSqr)
+	perfVarSum := selfTeam.Accum(gi.PerformanceVarianceSum) + otherTeam.Accum(gi.PerformanceVarianceSum)
+	c := math.Sqrt(selfTeam.Accum(skills.VarianceSum) + otherTeam.Accum(skills.VarianceSum) + perfVarSum + adv.Variance())
 
 	winningMean := selfMeanSum
 	losingMean := otherMeanSum
@@ -58,20 +91,15 @@ func twoTeamUpdateRatings(gi *skills.GameInfo, newSkills skills.PlayerRatings, s
 
 	meanDelta := winningMean - losingMean
 
-	var v, w, rankMultiplier float64
+	v, w := corrections(gi, meanDelta, drawMargin, c, comparison == skills.Draw)
 
+	rankMultiplier := 1.0
 	if comparison != skills.Draw {
-		v = vExceedsMarginC(meanDelta, drawMargin, c)
-		w = wExceedsMarginC(meanDelta, drawMargin, c)
 		rankMultiplier = float64(comparison)
-	} else {
-		v = vWithinMarginC(meanDelta, drawMargin, c)
-		w = wWithinMarginC(meanDelta, drawMargin, c)
-		rankMultiplier = 1
 	}
 
-	for p, r := range selfTeam.PlayerRatings {
-		prevPlayerRating := r
+	for _, p := range selfTeam.Players() {
+		prevPlayerRating := selfTeam.PlayerRating(p)
 
 		meanMultiplier := (prevPlayerRating.Variance() + tauSqr) / c
 		stdDevMultiplier := (prevPlayerRating.Variance() + tauSqr) / numerics.Sqr(c)
@@ -81,38 +109,93 @@ func twoTeamUpdateRatings(gi *skills.GameInfo, newSkills skills.PlayerRatings, s
 
 		newStdDev := math.Sqrt((prevPlayerRating.Variance() + tauSqr) * (1 - w*stdDevMultiplier))
 
-		newSkills[p] = skills.NewRating(newMean, newStdDev)
+		newRating := temperedRating(prevPlayerRating.Mean(), prevPlayerRating.Variance()+tauSqr, skills.NewRating(newMean, newStdDev), weight)
+		newSkills[p] = stamped(newRating, prevPlayerRating, at)
+
+		if explain != nil {
+			a, b := outlierWeights(gi, false)
+			explain[p] = &Explanation{
+				Prior:      prevPlayerRating,
+				Result:     comparison,
+				WinProb:    a*gameCurve(gi).Cdf((selfMeanSum-otherMeanSum-drawMargin)/c) + b,
+				MeanDelta:  meanDelta,
+				DrawMargin: drawMargin,
+				C:          c,
+				V:          v,
+				W:          w,
+				Share:      (prevPlayerRating.Variance() + tauSqr) / numerics.Sqr(c),
+				Weight:     weight,
+			}
+		}
+	}
+
+	return temperedRating(adv.Mean(), adv.Variance(), advantagePosterior(adv, v, w, c, rankMultiplier), weight)
+}
+
+// Rates the players of selfTeam who left the match as having lost to
+// otherTeam, whatever the result of their team.
+func twoTeamUpdateLeavers(gi *skills.GameInfo, newSkills skills.PlayerRatings, explain map[skills.Player]*Explanation, selfTeam, otherTeam skills.Team, adv skills.Rating, weight float64, at time.Time) {
+	if _, left := selfTeam.LeftAt(); !left {
+		return
+	}
+
+	losses := make(skills.PlayerRatings)
+	var lossExplain map[skills.Player]*Explanation
+	if explain != nil {
+		lossExplain = make(map[skills.Player]*Explanation)
+	}
+	twoTeamUpdateRatings(gi, losses, lossExplain, selfTeam, otherTeam, adv, skills.Lose, weight, at)
+	for _, p := range selfTeam.Players() {
+		if selfTeam.Attrs(p).Status != skills.Completed {
+			newSkills[p] = losses[p]
+			if explain != nil {
+				explain[p] = lossExplain[p]
+			}
+		}
 	}
 }
 
 // Calculates the match quality as the likelihood of all teams drawing (0% = bad, 100% = well matched).
+// The first team's performance includes the game's fixed advantage.
 func (calc *TwoTeamCalc) CalcMatchQual(gi *skills.GameInfo, teams []skills.Team) float64 {
 	// Basic argument checking
 	validateTeamCount(teams, twoTeamTeamRange)
 	validatePlayersPerTeam(teams, twoTeamPlayerRange)
+	return twoTeamMatchQual(gi, teams, skills.NewRating(gi.Advantage, 0))
+}
+
+// Calculates the match quality from the ratings CalcMatch rates the match
+// from, with the first team's advantage the match's own if it has one.
+func (calc *TwoTeamCalc) MatchQual(gi *skills.GameInfo, m *skills.Match) float64 {
+	validateTeamCount(m.Teams, twoTeamTeamRange)
+	validatePlayersPerTeam(m.Teams, twoTeamPlayerRange)
+	return twoTeamMatchQual(gi, gi.InactivePriors(m.Teams, m.Time), m.FirstTeamAdvantage(gi))
+}
+
+// Calculates the quality of a match between two teams where the first has
+// the given advantage, whose variance counts like that of a skill.
+func twoTeamMatchQual(gi *skills.GameInfo, teams []skills.Team, adv skills.Rating) float64 {
+	teams = anchoredTeams(teams)
 
 	// We've verified that there's just two teams
 	team1 := teams[0]
-	team1Count := team1.PlayerCount()
-
 	team2 := teams[1]
-	team2Count := team2.PlayerCount()
 
-	totalPlayers := team1Count + team2Count
-
-	betaSqr := numerics.Sqr(gi.Beta)
-
-	team1MeanSum := team1.Accum(skills.MeanSum)
-	team1VarSum := team1.Accum(skills.VarianceSum)
+	team1MeanSum := team1.Accum(skills.MeanSum) + adv.Mean()
+	team1VarSum := team1.Accum(skills.VarianceSum) + adv.Variance()
 
 	team2MeanSum := team2.Accum(skills.MeanSum)
 	team2VarSum := team2.Accum(skills.VarianceSum)
 
-	// This comes from equation 4.1 in the TrueSkill paper on page 8            
-	// The equation was broken up into the part under the square root sign and 
+	// This comes from equation 4.1 in the TrueSkill paper on page 8
+	// The equation was broken up into the part under the square root sign and
 	// the exponential part to make the code easier to read.
 
-	betaSqrPlayers := betaSqr * float64(totalPlayers)
+	betaSqrPlayers := team1.Accum(gi.PerformanceVarianceSum) + team2.Accum(gi.PerformanceVarianceSum)
+
+	if _, gauss := gameCurve(gi).(numerics.GaussCurve); !gauss || gi.OutlierProbability > 0 {
+		return drawProbRatio(gi, team1MeanSum-team2MeanSum, math.Sqrt(betaSqrPlayers+team1VarSum+team2VarSum), math.Sqrt(betaSqrPlayers))
+	}
 
 	sqrtPart := math.Sqrt(betaSqrPlayers / (betaSqrPlayers + team1VarSum + team2VarSum))
 	expPart := math.Exp(-.5 * numerics.Sqr(team1MeanSum-team2MeanSum) / (betaSqrPlayers + team1VarSum + team2VarSum))
@@ -120,6 +203,30 @@ func (calc *TwoTeamCalc) CalcMatchQual(gi *skills.GameInfo, teams []skills.Team)
 	return expPart * sqrtPart
 }
 
+// Returns the probability of a draw between two sides whose performance
+// difference has mean perfDiff and standard deviation c, under the game's
+// curve and outliers, relative to that of a draw between sides of equal and
+// exactly known skill, whose difference has standard deviation c0.
+//
+// Equation 4.1 is the limit of this ratio for the Gaussian as the draw margin
+// goes to 0, and it is so here for games without draws: the ratio of the
+// curve's densities at the difference, c0/c·f(perfDiff/c)/f(0). With draws
+// the game's draw margin is used, which for the Gaussian gives about equation
+// 4.1 for the usual draw probabilities, and outliers make a draw possible
+// however lopsided the match.
+func drawProbRatio(gi *skills.GameInfo, perfDiff, c, c0 float64) float64 {
+	curve := gameCurve(gi)
+	margin := gameDrawMargin(gi)
+	if margin <= 0 {
+		return c0 / c * curve.Pdf(perfDiff/c) / curve.Pdf(0)
+	}
+	a, b := outlierWeights(gi, true)
+	draw := func(perfDiff, c float64) float64 {
+		return a*(curve.Cdf((margin-perfDiff)/c)-curve.Cdf((-margin-perfDiff)/c)) + b
+	}
+	return draw(perfDiff, c) / draw(0, c0)
+}
+
 var (
 	twoTeamTeamRange   = numerics.Exactly(2)
 	twoTeamPlayerRange = numerics.AtLeast(1)
@@ -1,10 +1,528 @@
 package trueskill
 
 import (
+	"github.com/ChrisHines/GoSkills/skills"
+	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
 	"testing"
+	"time"
 )
 
 func TestTwoTeamCalc(t *testing.T) {
 	AllTwoPlayerScenarios(t, &TwoTeamCalc{})
 	AllTwoTeamScenarios(t, &TwoTeamCalc{})
 }
+
+func TestCalcOutcomeProbs(t *testing.T) {
+	for _, calc := range []skills.OutcomePredictor{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		gameInfo := skills.DefaultGameInfo
+
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*skills.NewPlayer(1), skills.NewRating(30, 0))
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*skills.NewPlayer(2), skills.NewRating(30, 0))
+
+		// With no uncertainty the draw probability is exactly the game's
+		win, draw, lose := calc.CalcOutcomeProbs(gameInfo, []skills.Team{team1, team2})
+		if math.Abs(draw-gameInfo.DrawProbability) > 1e-9 || math.Abs(win-lose) > 1e-9 {
+			t.Errorf("%T: win, draw, lose = %v, %v, %v, want a draw probability of %v", calc, win, draw, lose, gameInfo.DrawProbability)
+		}
+
+		team2 = skills.NewTeam()
+		team2.AddPlayer(*skills.NewPlayer(2), gameInfo.DefaultRating())
+		win, draw, lose = calc.CalcOutcomeProbs(gameInfo, []skills.Team{team1, team2})
+		if math.Abs(win+draw+lose-1) > 1e-9 || win <= lose {
+			t.Errorf("%T: win, draw, lose = %v, %v, %v, want them to sum to 1 and favor the stronger player", calc, win, draw, lose)
+		}
+	}
+}
+
+func TestInactivityGrowth(t *testing.T) {
+	for _, calc := range []skills.MatchCalc{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		gameInfo := *skills.DefaultGameInfo
+		gameInfo.InactivityRate = 0.1
+		gameInfo.InactivityCap = 20
+
+		now := time.Date(2013, 6, 1, 0, 0, 0, 0, time.UTC)
+		prior := skills.NewRating(25, 4)
+
+		// Rates a win against a player who just played, by a player
+		// last seen the given number of days ago
+		rate := func(days int) skills.Rating {
+			player1 := skills.NewPlayer(1)
+			player2 := skills.NewPlayer(2)
+
+			team1 := skills.NewTeam()
+			team1.AddPlayer(*player1, prior.PlayedAt(now.AddDate(0, 0, -days)))
+			team2 := skills.NewTeam()
+			team2.AddPlayer(*player2, prior.PlayedAt(now))
+
+			m := skills.NewMatch([]skills.Team{team1, team2}, 1, 2)
+			m.Time = now
+			newRatings := calc.CalcMatch(&gameInfo, m)
+
+			if lp := newRatings[*player2].LastPlayed(); !lp.Equal(now) {
+				t.Errorf("%T: LastPlayed = %v, want %v", calc, lp, now)
+			}
+			return newRatings[*player1]
+		}
+
+		yesterday, lastMonth, lastYear, lastDecade := rate(1), rate(30), rate(365), rate(3650)
+		if !(yesterday.Mean() < lastMonth.Mean() && lastMonth.Mean() < lastYear.Mean()) {
+			t.Errorf("%T: means after 1, 30 and 365 days = %v, %v, %v, want increasing gains", calc, yesterday.Mean(), lastMonth.Mean(), lastYear.Mean())
+		}
+		if !lastYear.Equal(lastDecade) {
+			t.Errorf("%T: ratings after 365 and 3650 days = %v, %v, want equal once capped", calc, lastYear, lastDecade)
+		}
+
+		// Without a match time nothing grows and the prior's time is kept
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*skills.NewPlayer(1), prior.PlayedAt(now.AddDate(-1, 0, 0)))
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*skills.NewPlayer(2), prior)
+		newRatings := calc.CalcNewRatings(&gameInfo, []skills.Team{team1, team2}, 1, 2)
+		plain := calc.CalcNewRatings(skills.DefaultGameInfo, []skills.Team{team1, team2}, 1, 2)
+		if r := newRatings[*skills.NewPlayer(1)]; !r.Equal(plain[*skills.NewPlayer(1)]) || !r.LastPlayed().Equal(now.AddDate(-1, 0, 0)) {
+			t.Errorf("%T: rating without match time = %v last played %v", calc, r, r.LastPlayed())
+		}
+
+		// The quality of a match grows the ratings as rating it does
+		m := skills.NewMatch([]skills.Team{team1, team2}, 1, 2)
+		m.Time = now
+		stale, fresh := m.Quality(calc, &gameInfo), calc.CalcMatchQual(&gameInfo, m.Teams)
+		if want := calc.CalcMatchQual(&gameInfo, gameInfo.InactivePriors(m.Teams, now)); stale != want || stale >= fresh {
+			t.Errorf("%T: quality with a stale player = %v, want %v and less than %v", calc, stale, want, fresh)
+		}
+	}
+}
+
+func TestAdvantage(t *testing.T) {
+	for _, calc := range []skills.MatchCalc{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		player1 := skills.NewPlayer(1)
+		player2 := skills.NewPlayer(2)
+
+		teams := func(mean1 float64) []skills.Team {
+			team1 := skills.NewTeam()
+			team1.AddPlayer(*player1, skills.NewRating(mean1, 6))
+			team2 := skills.NewTeam()
+			team2.AddPlayer(*player2, skills.NewRating(28, 5))
+			return []skills.Team{team1, team2}
+		}
+
+		// A fixed advantage acts like a higher mean for the first team
+		home := *skills.DefaultGameInfo
+		home.Advantage = 3
+		for _, ranks := range [][]int{{1, 2}, {2, 1}, {1, 1}} {
+			got := calc.CalcMatch(&home, skills.NewMatch(teams(25), ranks...))
+			want := calc.CalcMatch(skills.DefaultGameInfo, skills.NewMatch(teams(28), ranks...))
+			AssertRating(t, want[*player1].Mean()-3, want[*player1].Stddev(), got[*player1])
+			AssertRating(t, want[*player2].Mean(), want[*player2].Stddev(), got[*player2])
+		}
+		AssertMatchQuality(t, calc.CalcMatchQual(skills.DefaultGameInfo, teams(28)), calc.CalcMatchQual(&home, teams(25)))
+
+		// A certain learned advantage is the same as a fixed one and stays put
+		adv := skills.NewRating(3, 0)
+		m := skills.NewMatch(teams(25), 2, 1)
+		m.Advantage = &adv
+		got := calc.CalcMatch(skills.DefaultGameInfo, m)
+		want := calc.CalcMatch(&home, skills.NewMatch(teams(25), 2, 1))
+		AssertRating(t, want[*player1].Mean(), want[*player1].Stddev(), got[*player1])
+		AssertRating(t, 3, 0, adv)
+
+		// An uncertain one moves toward the side that won
+		prior := skills.NewRating(0, 2)
+		for _, ranks := range [][]int{{1, 2}, {2, 1}} {
+			adv = prior
+			m = skills.NewMatch(teams(25), ranks...)
+			m.Advantage = &adv
+			calc.CalcMatch(skills.DefaultGameInfo, m)
+
+			if won := ranks[0] < ranks[1]; won != (adv.Mean() > 0) {
+				t.Errorf("%T: advantage after ranks %v = %v", calc, ranks, adv)
+			}
+			if adv.Stddev() >= prior.Stddev() {
+				t.Errorf("%T: advantage stddev after ranks %v = %v, want less than %v", calc, ranks, adv.Stddev(), prior.Stddev())
+			}
+		}
+	}
+}
+
+func TestPlayerBeta(t *testing.T) {
+	for _, calc := range []skills.Calc{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		player1 := skills.NewPlayer(1)
+		player2 := skills.NewPlayer(2)
+		gameInfo := skills.DefaultGameInfo
+
+		teams := func(beta float64) []skills.Team {
+			team1 := skills.NewTeam()
+			team1.AddPlayer(*player1, gameInfo.DefaultRating().WithBeta(beta))
+			team2 := skills.NewTeam()
+			team2.AddPlayer(*player2, gameInfo.DefaultRating())
+			return []skills.Team{team1, team2}
+		}
+
+		// A beta equal to the game's changes nothing
+		want := calc.CalcNewRatings(gameInfo, teams(0), 1, 2)
+		got := calc.CalcNewRatings(gameInfo, teams(gameInfo.Beta), 1, 2)
+		AssertRating(t, want[*player1].Mean(), want[*player1].Stddev(), got[*player1])
+		AssertMatchQuality(t, calc.CalcMatchQual(gameInfo, teams(0)), calc.CalcMatchQual(gameInfo, teams(gameInfo.Beta)))
+
+		// A volatile player's win says less about either player
+		got = calc.CalcNewRatings(gameInfo, teams(3*gameInfo.Beta), 1, 2)
+		if got[*player1].Mean() >= want[*player1].Mean() || got[*player2].Mean() <= want[*player2].Mean() {
+			t.Errorf("%T: ratings with a volatile winner = %v, want smaller changes than %v", calc, got, want)
+		}
+		if beta := got[*player1].Beta(); beta != 3*gameInfo.Beta {
+			t.Errorf("%T: new rating has beta %v, want the prior's %v", calc, beta, 3*gameInfo.Beta)
+		}
+	}
+}
+
+func TestOutlierProbability(t *testing.T) {
+	for _, calc := range []skills.Calc{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		player1 := skills.NewPlayer(1)
+		player2 := skills.NewPlayer(2)
+		gameInfo := *skills.DefaultGameInfo
+		gameInfo.OutlierProbability = 0.05
+
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*player1, gameInfo.DefaultRating())
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*player2, skills.NewRating(60, 4))
+		teams := []skills.Team{team1, team2}
+
+		// Massive upsets move ratings far less than in the Gaussian model
+		for _, ranks := range [][]int{{1, 1}, {1, 2}} {
+			want := calc.CalcNewRatings(skills.DefaultGameInfo, teams, ranks...)
+			got := calc.CalcNewRatings(&gameInfo, teams, ranks...)
+			gained := got[*player1].Mean() - gameInfo.InitialMean
+			if gained <= 0 || gained >= (want[*player1].Mean()-gameInfo.InitialMean)/2 {
+				t.Errorf("%T: upset with ranks %v gained %v, Gaussian model gained %v", calc, ranks, gained, want[*player1].Mean()-gameInfo.InitialMean)
+			}
+		}
+
+		// Expected results hardly differ
+		want := calc.CalcNewRatings(skills.DefaultGameInfo, teams, 2, 1)
+		got := calc.CalcNewRatings(&gameInfo, teams, 2, 1)
+		for _, p := range []*skills.Player{player1, player2} {
+			if d := math.Abs(got[*p].Mean() - want[*p].Mean()); d > 0.1 {
+				t.Errorf("%T: expected result moved player %v %v more than the Gaussian model", calc, p, d)
+			}
+		}
+
+		// Every outcome keeps some probability
+		win, draw, lose := calc.(skills.OutcomePredictor).CalcOutcomeProbs(&gameInfo, teams)
+		if math.Abs(win+draw+lose-1) > 1e-9 || win < gameInfo.OutlierProbability*(1-gameInfo.DrawProbability)/2 {
+			t.Errorf("%T: win, draw, lose = %v, %v, %v", calc, win, draw, lose)
+		}
+	}
+}
+
+func TestLogisticCurve(t *testing.T) {
+	for _, calc := range []skills.Calc{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		player1 := skills.NewPlayer(1)
+		player2 := skills.NewPlayer(2)
+		gameInfo := *skills.DefaultGameInfo
+
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*player1, gameInfo.DefaultRating())
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*player2, skills.NewRating(30, 0))
+		teams := []skills.Team{team1, team2}
+
+		// The Gaussian curve gives the same results as no curve
+		gameInfo.Curve = numerics.GaussCurve{}
+		for _, ranks := range [][]int{{1, 2}, {1, 1}} {
+			want := calc.CalcNewRatings(skills.DefaultGameInfo, teams, ranks...)
+			got := calc.CalcNewRatings(&gameInfo, teams, ranks...)
+			AssertRating(t, want[*player1].Mean(), want[*player1].Stddev(), got[*player1])
+		}
+		AssertMatchQuality(t, calc.CalcMatchQual(skills.DefaultGameInfo, teams), calc.CalcMatchQual(&gameInfo, teams))
+
+		// The logistic curve keeps the game's draw probability
+		gameInfo.Curve = numerics.LogisticCurve{}
+		even := skills.NewTeam()
+		even.AddPlayer(*player1, skills.NewRating(30, 0))
+		_, draw, _ := calc.(skills.OutcomePredictor).CalcOutcomeProbs(&gameInfo, []skills.Team{even, team2})
+		if math.Abs(draw-gameInfo.DrawProbability) > 1e-9 {
+			t.Errorf("%T: logistic draw probability = %v, want %v", calc, draw, gameInfo.DrawProbability)
+		}
+
+		// and its heavier tails make upsets move ratings less than the
+		// Gaussian's, without changing the direction of the updates
+		upset := skills.NewTeam()
+		upset.AddPlayer(*player1, skills.NewRating(10, 4))
+		want := calc.CalcNewRatings(skills.DefaultGameInfo, []skills.Team{upset, team2}, 1, 2)
+		got := calc.CalcNewRatings(&gameInfo, []skills.Team{upset, team2}, 1, 2)
+		if gained := got[*player1].Mean() - 10; gained <= 0 || gained >= want[*player1].Mean()-10 {
+			t.Errorf("%T: logistic upset gained %v, Gaussian gained %v", calc, gained, want[*player1].Mean()-10)
+		}
+		if q := calc.CalcMatchQual(&gameInfo, teams); q <= 0 || q >= 1 {
+			t.Errorf("%T: logistic match quality = %v", calc, q)
+		}
+	}
+}
+
+func TestLogisticDraw(t *testing.T) {
+	for _, calc := range []skills.Calc{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		gameInfo := *skills.DefaultGameInfo
+		gameInfo.Curve = numerics.LogisticCurve{}
+
+		// A draw with a much more certain opponent narrows the window of the
+		// logistic likelihood enough to curve it more sharply than a Gaussian
+		for _, sigma := range []float64{1, 3} {
+			team1 := skills.NewTeam()
+			team1.AddPlayer(*skills.NewPlayer(1), gameInfo.DefaultRating())
+			team2 := skills.NewTeam()
+			team2.AddPlayer(*skills.NewPlayer(2), skills.NewRating(25, sigma))
+
+			for p, r := range calc.CalcNewRatings(&gameInfo, []skills.Team{team1, team2}, 1, 1) {
+				if s := r.Stddev(); math.IsNaN(r.Mean()) || math.IsNaN(s) || s <= 0 {
+					t.Errorf("%T: rating of %v after a logistic draw with stddev %v = %v", calc, p, sigma, r)
+				}
+			}
+		}
+	}
+}
+
+func TestMatchWeight(t *testing.T) {
+	for _, calc := range []skills.MatchCalc{&TwoPlayerCalc{}, &TwoTeamCalc{}, &FFACalc{}} {
+		player1 := skills.NewPlayer(1)
+		player2 := skills.NewPlayer(2)
+		gameInfo := skills.DefaultGameInfo
+
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*player1, skills.NewRating(25, 6))
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*player2, skills.NewRating(30, 4))
+		teams := []skills.Team{team1, team2}
+
+		rate := func(weight float64) skills.Rating {
+			m := skills.NewMatch(teams, 1, 2)
+			m.Weight = &weight
+			return calc.CalcMatch(gameInfo, m)[*player1]
+		}
+
+		// Weight 0 leaves ratings unchanged and weight 1 is the usual update
+		AssertRating(t, 25, 6, rate(0))
+		want := calc.CalcNewRatings(gameInfo, teams, 1, 2)[*player1]
+		AssertRating(t, want.Mean(), want.Stddev(), rate(1))
+
+		// and so is a match without a weight
+		literal := calc.CalcMatch(gameInfo, &skills.Match{Teams: teams, Ranks: []int{1, 2}})[*player1]
+		AssertRating(t, want.Mean(), want.Stddev(), literal)
+
+		// Other weights scale the update
+		half, double := rate(0.5), rate(2)
+		if !(25 < half.Mean() && half.Mean() < want.Mean() && want.Mean() < double.Mean()) {
+			t.Errorf("%T: means for weights 0.5, 1, 2 = %v, %v, %v", calc, half.Mean(), want.Mean(), double.Mean())
+		}
+		if !(double.Stddev() < want.Stddev() && want.Stddev() < half.Stddev() && half.Stddev() < 6) {
+			t.Errorf("%T: stddevs for weights 0.5, 1, 2 = %v, %v, %v", calc, half.Stddev(), want.Stddev(), double.Stddev())
+		}
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%T: negative weight did not panic", calc)
+				}
+			}()
+			rate(-1)
+		}()
+	}
+}
+
+func TestAnchoredPlayers(t *testing.T) {
+	for _, calc := range []skills.MatchCalc{&TwoPlayerCalc{}, &TwoTeamCalc{}, &FFACalc{}} {
+		gameInfo := *skills.DefaultGameInfo
+		gameInfo.InactivityRate = 0.1
+
+		now := time.Date(2013, 6, 1, 0, 0, 0, 0, time.UTC)
+		human := skills.NewPlayer(1)
+		bot := skills.NewPlayer(2)
+		botRating := skills.NewRating(30, 4).PlayedAt(now.AddDate(-1, 0, 0))
+
+		teams := func(botSkill skills.Rating, anchor skills.Anchor) []skills.Team {
+			team1 := skills.NewTeam()
+			team1.AddPlayer(*human, skills.NewRating(25, 6))
+			team2 := skills.NewTeam()
+			team2.AddPlayerAttrs(*bot, botSkill, skills.PlayerAttrs{Weight: 1, Anchor: anchor})
+			return []skills.Team{team1, team2}
+		}
+		rate := func(botSkill skills.Rating, anchor skills.Anchor) skills.PlayerRatings {
+			m := skills.NewMatch(teams(botSkill, anchor), 1, 2)
+			m.Time = now
+			return calc.CalcMatch(&gameInfo, m)
+		}
+
+		// The bot keeps its prior, without growth or a new time, and the
+		// human is rated against that prior as usual
+		anchored := rate(botRating, skills.Anchored)
+		if r := anchored[*bot]; r != botRating {
+			t.Errorf("%T: anchored rating = %v last played %v, want %v", calc, r, r.LastPlayed(), botRating)
+		}
+		want := rate(skills.NewRating(30, 4), skills.NotAnchored)[*human]
+		AssertRating(t, want.Mean(), want.Stddev(), anchored[*human])
+
+		// An exact anchor counts as having no uncertainty
+		exact := rate(botRating, skills.AnchoredExact)
+		if r := exact[*bot]; r != botRating {
+			t.Errorf("%T: exactly anchored rating = %v, want %v", calc, r, botRating)
+		}
+		want = rate(skills.NewRating(30, 0), skills.NotAnchored)[*human]
+		AssertRating(t, want.Mean(), want.Stddev(), exact[*human])
+
+		wantQual := calc.CalcMatchQual(&gameInfo, teams(skills.NewRating(30, 0), skills.NotAnchored))
+		AssertMatchQuality(t, wantQual, calc.CalcMatchQual(&gameInfo, teams(botRating, skills.AnchoredExact)))
+	}
+}
+
+func TestLeavers(t *testing.T) {
+	calc := &TwoTeamCalc{}
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.Leavers = &skills.LeaverPolicy{Teammates: 0, Opponents: 0.5}
+
+	ann, bob, cat, dan := skills.NewPlayer("ann"), skills.NewPlayer("bob"), skills.NewPlayer("cat"), skills.NewPlayer("dan")
+	prior := skills.NewRating(25, 6)
+
+	// Rates ann and bob against cat and dan, where dan has the given status
+	rate := func(gi *skills.GameInfo, status skills.Status, leftAt float64, ranks ...int) skills.PlayerRatings {
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*ann, prior)
+		team1.AddPlayer(*bob, prior)
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*cat, prior)
+		team2.AddPlayerAttrs(*dan, prior, skills.PlayerAttrs{Weight: 1, Status: status, LeftAt: leftAt})
+		return calc.CalcNewRatings(gi, []skills.Team{team1, team2}, ranks...)
+	}
+	plain := rate(skills.DefaultGameInfo, skills.Completed, 1, 1, 2)
+
+	// Without leavers the policy changes nothing
+	for p, r := range rate(&gameInfo, skills.Completed, 1, 1, 2) {
+		AssertRating(t, plain[p].Mean(), plain[p].Stddev(), r)
+	}
+
+	// An AFK player takes the loss alone, and the win counts for half
+	afk := rate(&gameInfo, skills.AFK, 1, 1, 2)
+	AssertRating(t, plain[*dan].Mean(), plain[*dan].Stddev(), afk[*dan])
+	AssertRating(t, 25, 6, afk[*cat])
+	if r := afk[*ann]; !(25 < r.Mean() && r.Mean() < plain[*ann].Mean() && plain[*ann].Stddev() < r.Stddev()) {
+		t.Errorf("ann after a win against a leaver = %v, plain win = %v", r, plain[*ann])
+	}
+
+	// A leaver loses even if their team wins
+	if r := rate(&gameInfo, skills.AFK, 1, 2, 1)[*dan]; r.Mean() >= 25 {
+		t.Errorf("dan after leaving a won match = %v", r)
+	}
+
+	// Leaving late spares the teammates less
+	late := rate(&gameInfo, skills.Left, 0.5, 1, 2)
+	if r := late[*cat]; !(plain[*cat].Mean() < r.Mean() && r.Mean() < 25) {
+		t.Errorf("cat after dan left half way = %v, plain loss = %v", r, plain[*cat])
+	}
+	if a, b := late[*ann].Mean(), afk[*ann].Mean(); !(b < a && a < plain[*ann].Mean()) {
+		t.Errorf("ann's mean after dan left half way = %v, want between %v and %v", a, b, plain[*ann].Mean())
+	}
+}
+
+func TestTwoPlayerLeavers(t *testing.T) {
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.Leavers = &skills.LeaverPolicy{Teammates: 0, Opponents: 0.5}
+	ann, bob := skills.NewPlayer("ann"), skills.NewPlayer("bob")
+	prior := skills.NewRating(25, 6)
+
+	teams := func(status skills.Status) []skills.Team {
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*ann, prior)
+		team2 := skills.NewTeam()
+		team2.AddPlayerAttrs(*bob, prior, skills.PlayerAttrs{Weight: 1, Status: status})
+		return []skills.Team{team1, team2}
+	}
+	plain := (&TwoPlayerCalc{}).CalcNewRatings(skills.DefaultGameInfo, teams(skills.Completed), 1, 2)
+
+	// The win over an AFK opponent counts for half
+	afk := (&TwoPlayerCalc{}).CalcNewRatings(&gameInfo, teams(skills.AFK), 1, 2)
+	if r := afk[*ann]; !(25 < r.Mean() && r.Mean() < plain[*ann].Mean()) {
+		t.Errorf("ann after a win against a leaver = %v, plain win = %v", r, plain[*ann])
+	}
+	AssertRating(t, plain[*bob].Mean(), plain[*bob].Stddev(), afk[*bob])
+
+	// and the AFK player loses even if they are ranked first
+	if r := (&TwoPlayerCalc{}).CalcNewRatings(&gameInfo, teams(skills.AFK), 2, 1)[*bob]; r.Mean() >= 25 {
+		t.Errorf("bob after leaving a won match = %v", r)
+	}
+
+}
+
+func TestMatchQualAdvantage(t *testing.T) {
+	for _, calc := range []skills.MatchCalc{&TwoPlayerCalc{}, &TwoTeamCalc{}} {
+		gameInfo := skills.DefaultGameInfo
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*skills.NewPlayer(1), skills.NewRating(20, 3))
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*skills.NewPlayer(2), skills.NewRating(25, 3))
+		m := skills.NewMatch([]skills.Team{team1, team2}, 1, 2)
+
+		// Without an advantage of its own the match's quality is the plain one
+		plain := calc.CalcMatchQual(gameInfo, m.Teams)
+		if q := m.Quality(calc, gameInfo); q != plain {
+			t.Errorf("%T: quality = %v, want %v", calc, q, plain)
+		}
+
+		// An advantage evening the match out raises its quality, unless it
+		// is too uncertain
+		adv := skills.NewRating(5, 0)
+		m.Advantage = &adv
+		even := m.Quality(calc, gameInfo)
+		adv = skills.NewRating(5, 10)
+		vague := m.Quality(calc, gameInfo)
+		if !(even > plain && vague < even) {
+			t.Errorf("%T: quality with advantage = %v, with uncertain advantage %v, without %v", calc, even, vague, plain)
+		}
+	}
+}
+
+func TestCurveMatchQuality(t *testing.T) {
+	calc := &TwoTeamCalc{}
+	teams := func(mean1, mean2, stddev float64) []skills.Team {
+		team1 := skills.NewTeam()
+		team1.AddPlayer(*skills.NewPlayer(1), skills.NewRating(mean1, stddev))
+		team2 := skills.NewTeam()
+		team2.AddPlayer(*skills.NewPlayer(2), skills.NewRating(mean2, stddev))
+		return []skills.Team{team1, team2}
+	}
+	plain := calc.CalcMatchQual(skills.DefaultGameInfo, teams(20, 25, 3))
+
+	// Equally skilled players known exactly make the best match
+	for _, curve := range []numerics.Curve{numerics.GaussCurve{}, numerics.LogisticCurve{}} {
+		gameInfo := *skills.DefaultGameInfo
+		gameInfo.Curve = curve
+		gameInfo.OutlierProbability = 0.05
+		AssertMatchQuality(t, 1, calc.CalcMatchQual(&gameInfo, teams(25, 25, 0)))
+	}
+
+	// With rare draws and outliers the Gaussian gives equation 4.1
+	gameInfo := *skills.DefaultGameInfo
+	gameInfo.DrawProbability = 1e-6
+	gameInfo.OutlierProbability = 1e-12
+	if q := calc.CalcMatchQual(&gameInfo, teams(20, 25, 3)); math.Abs(q-plain) > 1e-4 {
+		t.Errorf("quality with rare outliers = %v, want %v", q, plain)
+	}
+
+	// and with the game's draws about it
+	gameInfo = *skills.DefaultGameInfo
+	gameInfo.Curve = numerics.GaussCurve{}
+	gameInfo.OutlierProbability = 1e-12
+	if q := calc.CalcMatchQual(&gameInfo, teams(20, 25, 3)); math.Abs(q-plain) > 0.01 {
+		t.Errorf("quality with draws and rare outliers = %v, want about %v", q, plain)
+	}
+
+	// Outliers make a lopsided match less hopeless
+	gameInfo = *skills.DefaultGameInfo
+	gameInfo.Curve = numerics.LogisticCurve{}
+	lopsided := calc.CalcMatchQual(&gameInfo, teams(10, 40, 1))
+	gameInfo.OutlierProbability = 0.1
+	if q := calc.CalcMatchQual(&gameInfo, teams(10, 40, 1)); q <= lopsided || q > 1 {
+		t.Errorf("lopsided quality with outliers = %v, without %v", q, lopsided)
+	}
+}
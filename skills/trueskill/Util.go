@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/ChrisHines/GoSkills/skills"
 	"github.com/ChrisHines/GoSkills/skills/numerics"
+	"math"
+	"time"
 )
 
 func validateTeamCount(teams []skills.Team, teamsAllowed numerics.Range) {
@@ -20,9 +22,128 @@ func validatePlayersPerTeam(teams []skills.Team, playersAllowed numerics.Range)
 	}
 }
 
+func validateWeight(weight float64) {
+	if weight < 0 || math.IsNaN(weight) {
+		panic(fmt.Errorf("match weight [%v] less than 0", weight))
+	}
+}
+
+// Returns the dynamics variance added to priors in a match of the given weight.
+func dynamicsVariance(gi *skills.GameInfo, weight float64) float64 {
+	return math.Min(weight, 1) * numerics.Sqr(gi.DynamicsFactor)
+}
+
+// Returns a prior with the dynamics of a match of the given weight added.
+func withDynamics(gi *skills.GameInfo, r skills.Rating, weight float64) skills.Rating {
+	return skills.NewRating(r.Mean(), math.Sqrt(r.Variance()+dynamicsVariance(gi, weight)))
+}
+
+// Tempers the update of a rating from a prior (with dynamics added) to a
+// posterior by a weight: the message of the match, the posterior divided by
+// the prior in precision and precision-mean, is raised to the power weight.
+func tempered(priorMean, priorVar, newMean, newVar, weight float64) (mean, variance float64) {
+	if weight == 1 {
+		return newMean, newVar
+	}
+	precision := 1/priorVar + weight*(1/newVar-1/priorVar)
+	precisionMean := priorMean/priorVar + weight*(newMean/newVar-priorMean/priorVar)
+	return precisionMean / precision, 1 / precision
+}
+
+// Tempers the update of a rating by a weight; see tempered.
+func temperedRating(priorMean, priorVar float64, r skills.Rating, weight float64) skills.Rating {
+	if weight == 1 {
+		return r
+	}
+	mean, variance := tempered(priorMean, priorVar, r.Mean(), r.Variance(), weight)
+	return skills.NewRating(mean, math.Sqrt(variance))
+}
+
+// Returns copies of the teams where exactly anchored players have a standard
+// deviation of 0, or the teams themselves if there are none.
+func anchoredTeams(teams []skills.Team) []skills.Team {
+	exact := false
+	for _, t := range teams {
+		for _, p := range t.Players() {
+			exact = exact || t.Attrs(p).Anchor == skills.AnchoredExact
+		}
+	}
+	if !exact {
+		return teams
+	}
+
+	ateams := make([]skills.Team, len(teams))
+	for i, t := range teams {
+		ateams[i] = t.MapRatings(func(p skills.Player, r skills.Rating) skills.Rating {
+			if t.Attrs(p).Anchor != skills.AnchoredExact {
+				return r
+			}
+			return skills.NewRating(r.Mean(), 0).WithBeta(r.Beta()).PlayedAt(r.LastPlayed())
+		})
+	}
+	return ateams
+}
+
+// Replaces the new ratings of anchored players with their priors.
+func keepAnchors(newSkills skills.PlayerRatings, priors []skills.Team) {
+	for _, t := range priors {
+		for _, p := range t.Players() {
+			if t.Attrs(p).Anchor != skills.NotAnchored {
+				newSkills[p] = t.PlayerRating(p)
+			}
+		}
+	}
+}
+
 func cond(c bool, t, f int) int {
 	if c {
 		return t
 	}
 	return f
 }
+
+// Stamps a new rating with the time of the match, or with the prior's last
+// played time if the match has no time. The prior's beta is carried over.
+func stamped(r, prior skills.Rating, at time.Time) skills.Rating {
+	r = r.WithBeta(prior.Beta())
+	if at.IsZero() {
+		return r.PlayedAt(prior.LastPlayed())
+	}
+	return r.PlayedAt(at)
+}
+
+// Returns the advantage of the first of the sorted teams over the second,
+// given the advantage of the first team in the order passed to the calc.
+// Sorting two teams swaps them only if the second is ranked better.
+func sortedAdvantage(adv skills.Rating, ranks []int) skills.Rating {
+	if ranks[1] < ranks[0] {
+		return negated(adv)
+	}
+	return adv
+}
+
+func negated(r skills.Rating) skills.Rating {
+	return skills.NewRating(-r.Mean(), r.Stddev())
+}
+
+// Updates a team's advantage like the rating of one of its players, but
+// without any performance noise or dynamics.
+func advantagePosterior(adv skills.Rating, v, w, c, rankMultiplier float64) skills.Rating {
+	variance := adv.Variance()
+	newMean := adv.Mean() + rankMultiplier*variance/c*v
+	newStdDev := math.Sqrt(variance * (1 - w*variance/numerics.Sqr(c)))
+	return skills.NewRating(newMean, newStdDev)
+}
+
+// Stores the posterior of the first team's advantage back in the match, if
+// the match has a learned advantage.
+func setAdvantage(m *skills.Match, winnerAdv, loserAdv skills.Rating) {
+	if m.Advantage == nil {
+		return
+	}
+	if m.Ranks[1] < m.Ranks[0] {
+		*m.Advantage = loserAdv
+	} else {
+		*m.Advantage = winnerAdv
+	}
+}